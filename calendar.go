@@ -0,0 +1,166 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+)
+
+const (
+	// calendarBaseYear anchors every recurring event. It is a leap year so
+	// that 02-29 events have a valid first occurrence.
+	calendarBaseYear = 2000
+	icsLineLimit     = 75
+)
+
+// listNamedays returns a country's namedays ordered by date, optionally
+// limited to names whose slug is in slugs
+func listNamedays(db *sql.DB, country string, slugs map[string]bool) ([]NameOccurrence, error) {
+	rows, err := db.Query("SELECT name, date, slug FROM namedays WHERE country = ? ORDER BY date, id", country)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	occurrences := []NameOccurrence{}
+	for rows.Next() {
+		var o NameOccurrence
+		var s string
+		if err := rows.Scan(&o.Name, &o.Date, &s); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		if len(slugs) > 0 && !slugs[s] {
+			continue
+		}
+		occurrences = append(occurrences, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return occurrences, nil
+}
+
+// RenderICalendar renders a country's namedays as an RFC 5545 calendar of
+// yearly recurring all-day events
+func RenderICalendar(country string, occurrences []NameOccurrence, now time.Time) (string, error) {
+	var sb strings.Builder
+	writeICSLine(&sb, "BEGIN:VCALENDAR")
+	writeICSLine(&sb, "VERSION:2.0")
+	writeICSLine(&sb, "PRODID:-//zrks//namedays//EN")
+	writeICSLine(&sb, "CALSCALE:GREGORIAN")
+	writeICSLine(&sb, "METHOD:PUBLISH")
+	writeICSLine(&sb, "X-WR-CALNAME:Namedays ("+strings.ToUpper(country)+")")
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, o := range occurrences {
+		start, err := time.Parse("2006-01-02", fmt.Sprintf("%d-%s", calendarBaseYear, o.Date))
+		if err != nil {
+			return "", fmt.Errorf("invalid date %q for %s: %w", o.Date, o.Name, err)
+		}
+
+		// 02-29 falls back to the last day of February in common years
+		rrule := "RRULE:FREQ=YEARLY"
+		if o.Date == "02-29" {
+			rrule = "RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1"
+		}
+
+		writeICSLine(&sb, "BEGIN:VEVENT")
+		writeICSLine(&sb, "UID:"+icsUID(country, o))
+		writeICSLine(&sb, "DTSTAMP:"+stamp)
+		writeICSLine(&sb, "DTSTART;VALUE=DATE:"+start.Format("20060102"))
+		writeICSLine(&sb, "DTEND;VALUE=DATE:"+start.AddDate(0, 0, 1).Format("20060102"))
+		writeICSLine(&sb, rrule)
+		writeICSLine(&sb, "SUMMARY:"+escapeICSText("Nameday: "+o.Name))
+		writeICSLine(&sb, "TRANSP:TRANSPARENT")
+		writeICSLine(&sb, "END:VEVENT")
+	}
+
+	writeICSLine(&sb, "END:VCALENDAR")
+	return sb.String(), nil
+}
+
+// icsUID derives a UID from the country, date and name so that re-imported
+// feeds update existing events instead of duplicating them
+func icsUID(country string, o NameOccurrence) string {
+	return fmt.Sprintf("%s-%s-%s@namedays", country, strings.ReplaceAll(o.Date, "-", ""), slug.Make(o.Name))
+}
+
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine writes a content line, folding it at 75 octets without
+// splitting multi-byte characters
+func writeICSLine(sb *strings.Builder, line string) {
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		sb.WriteString(line[:cut])
+		sb.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards the limit
+		limit = icsLineLimit - 1
+	}
+	sb.WriteString(line)
+	sb.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+type calendarHandler struct {
+	db *sql.DB
+}
+
+func NewCalendarHandler(db *sql.DB) *calendarHandler {
+	return &calendarHandler{db: db}
+}
+
+func (h *calendarHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	slugs := make(map[string]bool)
+	if names := r.URL.Query().Get("names"); names != "" {
+		for _, name := range strings.Split(names, ",") {
+			if s := slug.Make(name); s != "" {
+				slugs[s] = true
+			}
+		}
+	}
+
+	occurrences, err := listNamedays(h.db, country, slugs)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	calendar, err := RenderICalendar(country, occurrences, time.Now())
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="namedays.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(calendar))
+}
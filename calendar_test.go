@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderICalendar(t *testing.T) {
+	occurrences := []NameOccurrence{
+		{Name: "Jānis", Date: "06-24"},
+		{Name: "Kazmirina,Sidars", Date: "03-04"},
+	}
+
+	calendar, err := RenderICalendar("lv", occurrences, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("RenderICalendar returned an error: %v", err)
+	}
+
+	expected := []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:lv-0624-janis@namedays\r\n",
+		"DTSTART;VALUE=DATE:20000624\r\n",
+		"DTEND;VALUE=DATE:20000625\r\n",
+		"RRULE:FREQ=YEARLY\r\n",
+		"SUMMARY:Nameday: Jānis\r\n",
+		`SUMMARY:Nameday: Kazmirina\,Sidars` + "\r\n",
+		"END:VCALENDAR\r\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(calendar, e) {
+			t.Errorf("Calendar does not contain %q", e)
+		}
+	}
+	if n := strings.Count(calendar, "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("Expected 2 events, got %d", n)
+	}
+}
+
+func TestRenderICalendarLeapDay(t *testing.T) {
+	calendar, err := RenderICalendar("lv", []NameOccurrence{{Name: "Leap", Date: "02-29"}}, time.Now())
+	if err != nil {
+		t.Fatalf("RenderICalendar returned an error: %v", err)
+	}
+
+	if !strings.Contains(calendar, "DTSTART;VALUE=DATE:20000229\r\n") {
+		t.Error("Expected leap day event to start on 2000-02-29")
+	}
+	if !strings.Contains(calendar, "RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1\r\n") {
+		t.Error("Expected leap day event to recur on the last day of February")
+	}
+}
+
+func TestWriteICSLineFolding(t *testing.T) {
+	var sb strings.Builder
+	writeICSLine(&sb, "SUMMARY:"+strings.Repeat("ā", 60))
+
+	for _, line := range strings.Split(strings.TrimSuffix(sb.String(), "\r\n"), "\r\n") {
+		if len(line) > icsLineLimit {
+			t.Errorf("Line exceeds %d octets: %d", icsLineLimit, len(line))
+		}
+		if !strings.HasPrefix(line, "SUMMARY:") && !strings.HasPrefix(line, " ") {
+			t.Errorf("Continuation line does not start with a space: %q", line)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(sb.String(), "\r\n ", "")
+	if unfolded != "SUMMARY:"+strings.Repeat("ā", 60)+"\r\n" {
+		t.Errorf("Unfolded line does not match the input")
+	}
+}
+
+func TestCalendarHandlerFiltersNames(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "06-24", "Jānis")
+	insertTestNames(t, db, "07-26", "Anna")
+	insertTestNames(t, db, "04-12", "Jūlijs")
+	handler := NewCalendarHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/calendar.ics?names=Anna,Janis", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	if ct := rr.Header().Get("Content-Type"); ct != "text/calendar; charset=utf-8" {
+		t.Errorf("Unexpected content type: %s", ct)
+	}
+
+	body := rr.Body.String()
+	if n := strings.Count(body, "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("Expected 2 events, got %d", n)
+	}
+	if strings.Contains(body, "Jūlijs") {
+		t.Error("Expected Jūlijs to be filtered out")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	_ "time/tzdata" // the Alpine image ships without a zoneinfo database
+)
+
+const (
+	DefaultTimezone = "Europe/Riga"
+	TimezoneHeader  = "X-Timezone"
+)
+
+// defaultLocation is the zone "today" is resolved in when a request does
+// not ask for another one
+var defaultLocation = mustLoadLocation(DefaultTimezone)
+
+// Clock reports the current time. Handlers hold one so tests can pin the date.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// fixedClock always reports the same instant
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// SetDefaultTimezone changes the zone used when a request does not specify one
+func SetDefaultTimezone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	defaultLocation = loc
+	return nil
+}
+
+// requestLocation returns the zone requested through the tz query parameter
+// or the X-Timezone header, falling back to the default zone
+func requestLocation(r *http.Request) (*time.Location, error) {
+	name := r.URL.Query().Get("tz")
+	if name == "" {
+		name = r.Header.Get(TimezoneHeader)
+	}
+	if name == "" {
+		return defaultLocation, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// requestNow returns the current time of clock in the zone requested by r
+func requestNow(clock Clock, r *http.Request) (time.Time, error) {
+	loc, err := requestLocation(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return clock.Now().In(loc), nil
+}
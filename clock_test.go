@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Half past ten in the evening UTC on June 23 is already June 24 in Riga
+var lateEveningUTC = fixedClock(time.Date(2024, time.June, 23, 22, 30, 0, 0, time.UTC))
+
+func createTestHomeHandler(t *testing.T) *homeHandler {
+	tmpDBPath, db := createTestDb(t)
+	insertTestNames(t, db, "06-23", "Līga")
+	insertTestNames(t, db, "06-24", "Jānis")
+
+	handler := NewHomeHandler(tmpDBPath)
+	handler.clock = lateEveningUTC
+	return handler
+}
+
+func TestRequestLocation(t *testing.T) {
+	_, req := setupTestRequest(t, http.MethodGet, "/", nil)
+	loc, err := requestLocation(req)
+	if err != nil || loc.String() != DefaultTimezone {
+		t.Errorf("Expected default zone %s, got %v (%v)", DefaultTimezone, loc, err)
+	}
+
+	_, req = setupTestRequest(t, http.MethodGet, "/?tz=America/New_York", nil)
+	req.Header.Set(TimezoneHeader, "Asia/Tokyo")
+	loc, err = requestLocation(req)
+	if err != nil || loc.String() != "America/New_York" {
+		t.Errorf("Expected query parameter to win, got %v (%v)", loc, err)
+	}
+
+	_, req = setupTestRequest(t, http.MethodGet, "/", nil)
+	req.Header.Set(TimezoneHeader, "Asia/Tokyo")
+	loc, err = requestLocation(req)
+	if err != nil || loc.String() != "Asia/Tokyo" {
+		t.Errorf("Expected header zone, got %v (%v)", loc, err)
+	}
+
+	_, req = setupTestRequest(t, http.MethodGet, "/?tz=Mars/Olympus", nil)
+	if _, err = requestLocation(req); err == nil {
+		t.Error("Expected an error for an unknown zone")
+	}
+}
+
+func TestHomeHandlerUsesDefaultTimezone(t *testing.T) {
+	handler := createTestHomeHandler(t)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	body := rr.Body.String()
+	if !strings.Contains(body, "Namedays for June 24") || !strings.Contains(body, "Jānis") {
+		t.Errorf("Expected Riga's June 24 namedays, got %s", body)
+	}
+}
+
+func TestHomeHandlerTimezoneOverride(t *testing.T) {
+	handler := createTestHomeHandler(t)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/?tz=UTC", nil)
+	handler.ServeHTTP(rr, req)
+	if body := rr.Body.String(); !strings.Contains(body, "Līga") {
+		t.Errorf("Expected UTC's June 23 namedays, got %s", body)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/", nil)
+	req.Header.Set(TimezoneHeader, "UTC")
+	handler.ServeHTTP(rr, req)
+	if body := rr.Body.String(); !strings.Contains(body, "Līga") {
+		t.Errorf("Expected UTC's June 23 namedays, got %s", body)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/?tz=Nowhere", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusBadRequest)
+}
+
+func TestDateHandlerPinnedClock(t *testing.T) {
+	_, db := createTestDb(t)
+	handler := NewDateHandler(db)
+	handler.clock = lateEveningUTC
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/date/tomorrow", nil)
+	handler.ServeHTTP(rr, req)
+	if result := decodeDateNamedays(t, rr.Body.Bytes()); result.Date != "06-25" {
+		t.Errorf("Expected tomorrow to be 06-25 in Riga, got %s", result.Date)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/api/v1/date/tomorrow?tz=UTC", nil)
+	handler.ServeHTTP(rr, req)
+	if result := decodeDateNamedays(t, rr.Body.Bytes()); result.Date != "06-24" {
+		t.Errorf("Expected tomorrow to be 06-24 in UTC, got %s", result.Date)
+	}
+}
+
+func TestSetDefaultTimezone(t *testing.T) {
+	original := defaultLocation
+	t.Cleanup(func() { defaultLocation = original })
+
+	if err := SetDefaultTimezone("Europe/Vilnius"); err != nil {
+		t.Fatalf("SetDefaultTimezone returned an error: %v", err)
+	}
+	if defaultLocation.String() != "Europe/Vilnius" {
+		t.Errorf("Expected Europe/Vilnius, got %s", defaultLocation)
+	}
+	if err := SetDefaultTimezone("Nowhere/Special"); err == nil {
+		t.Error("Expected an error for an unknown zone")
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+
+	"github.com/zrks/namedays/pkg/contacts"
+	"github.com/zrks/namedays/pkg/tabular"
+)
+
+const (
+	DefaultContactList = "default"
+	maxContactsUpload  = 1 << 20
+)
+
+var (
+	ContactListRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+)
+
+// ContactMatch is a contact together with every date their first name is
+// celebrated on. A name celebrated on several dates is flagged ambiguous.
+type ContactMatch struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	FirstName string   `json:"first_name"`
+	Email     string   `json:"email,omitempty"`
+	Dates     []string `json:"dates"`
+	Ambiguous bool     `json:"ambiguous"`
+}
+
+// UpcomingContact is a contact celebrating in the upcoming window
+type UpcomingContact struct {
+	ContactMatch
+	Date      string `json:"date"`
+	Year      int    `json:"year"`
+	DaysUntil int    `json:"days_until"`
+}
+
+// ContactList is the JSON representation of a contact list
+type ContactList struct {
+	List     string         `json:"list"`
+	Country  string         `json:"country"`
+	Contacts []ContactMatch `json:"contacts"`
+}
+
+// UpcomingContacts is the JSON representation of the contacts celebrating
+// in the next few days
+type UpcomingContacts struct {
+	List     string            `json:"list"`
+	Country  string            `json:"country"`
+	From     string            `json:"from"`
+	Days     int               `json:"days"`
+	Contacts []UpcomingContact `json:"contacts"`
+}
+
+// contactUploadErrors is the response to a contact file with invalid entries
+type contactUploadErrors struct {
+	Errors tabular.RowErrors `json:"errors"`
+}
+
+// saveContacts replaces the contents of a contact list
+func saveContacts(db *sql.DB, list string, people []contacts.Contact) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if _, err = tx.Exec("DELETE FROM contacts WHERE list = ?", list); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to replace contacts: %w", err)
+	}
+	for _, person := range people {
+		if _, err = tx.Exec("INSERT INTO contacts (list, name, first_name, slug, email) VALUES (?, ?, ?, ?, ?)", list, person.Name, person.FirstName, slug.Make(person.FirstName), person.Email); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to insert contact: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// matchContacts returns the contacts of a list with the dates their first
+// names are celebrated on in a country's calendar. Names are matched by
+// slug, like the reverse name lookup.
+func matchContacts(db *sql.DB, country, list string) ([]ContactMatch, error) {
+	rows, err := db.Query(`SELECT DISTINCT c.id, c.name, c.first_name, c.email, n.date
+		FROM contacts c LEFT JOIN namedays n ON n.slug = c.slug AND n.country = ?
+		WHERE c.list = ? ORDER BY c.id, n.date`, country, list)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []ContactMatch{}
+	for rows.Next() {
+		var m ContactMatch
+		var date sql.NullString
+		if err := rows.Scan(&m.ID, &m.Name, &m.FirstName, &m.Email, &date); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		if len(matches) == 0 || matches[len(matches)-1].ID != m.ID {
+			m.Dates = []string{}
+			matches = append(matches, m)
+		}
+		if date.Valid {
+			last := &matches[len(matches)-1]
+			last.Dates = append(last.Dates, date.String)
+			last.Ambiguous = len(last.Dates) > 1
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return matches, nil
+}
+
+// upcomingContacts returns the contacts celebrating in the n days starting
+// at from, soonest first. A contact whose name is celebrated twice in the
+// window is listed for each date.
+func upcomingContacts(matches []ContactMatch, from time.Time, n int) []UpcomingContact {
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	window := make(map[string]int, n)
+	for i := n - 1; i >= 0; i-- {
+		window[start.AddDate(0, 0, i).Format("01-02")] = i
+	}
+
+	upcoming := []UpcomingContact{}
+	for _, m := range matches {
+		for _, date := range m.Dates {
+			if i, ok := window[date]; ok {
+				upcoming = append(upcoming, UpcomingContact{
+					ContactMatch: m,
+					Date:         date,
+					Year:         start.AddDate(0, 0, i).Year(),
+					DaysUntil:    i,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		if upcoming[i].DaysUntil != upcoming[j].DaysUntil {
+			return upcoming[i].DaysUntil < upcoming[j].DaysUntil
+		}
+		return upcoming[i].Name < upcoming[j].Name
+	})
+	return upcoming
+}
+
+// readContacts parses an uploaded contact file. The format comes from the
+// format parameter or the Content-Type, and is otherwise sniffed from the
+// body.
+func readContacts(w http.ResponseWriter, r *http.Request) ([]contacts.Contact, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxContactsUpload))
+	if err != nil {
+		return nil, fmt.Errorf("error reading contacts: %w", err)
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/vcard", "text/x-vcard", "text/directory":
+			format = "vcard"
+		case "text/csv":
+			format = "csv"
+		case "text/tab-separated-values":
+			format = "tsv"
+		case "", "text/plain", "application/octet-stream":
+			format = "csv"
+			trimmed := strings.TrimSpace(strings.TrimPrefix(string(body), "\ufeff"))
+			if len(trimmed) >= 11 && strings.EqualFold(trimmed[:11], "BEGIN:VCARD") {
+				format = "vcard"
+			}
+		default:
+			return nil, fmt.Errorf("unsupported content type %q", mediaType)
+		}
+	}
+
+	switch format {
+	case "vcard":
+		return contacts.ReadVCard(bytes.NewReader(body))
+	case "csv":
+		return contacts.ReadCSV(bytes.NewReader(body), ',')
+	case "tsv":
+		return contacts.ReadCSV(bytes.NewReader(body), '\t')
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// requestContactList returns the contact list named by the list parameter
+func requestContactList(r *http.Request) (string, error) {
+	list := r.URL.Query().Get("list")
+	if list == "" {
+		return DefaultContactList, nil
+	}
+	if !ContactListRe.MatchString(list) {
+		return "", fmt.Errorf("invalid contact list %q", list)
+	}
+	return list, nil
+}
+
+type contactsHandler struct {
+	db *sql.DB
+}
+
+func NewContactsHandler(db *sql.DB) *contactsHandler {
+	return &contactsHandler{db: db}
+}
+
+func (h *contactsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	list, err := requestContactList(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		people, err := readContacts(w, r)
+		if rowErrors, ok := err.(tabular.RowErrors); ok {
+			writeJSON(w, r, http.StatusBadRequest, contactUploadErrors{Errors: rowErrors})
+			return
+		}
+		if err != nil {
+			BadRequestHandler(w, r)
+			return
+		}
+		if err = saveContacts(h.db, list, people); err != nil {
+			InternalServerErrorHandler(w, r)
+			return
+		}
+	}
+
+	matches, err := matchContacts(h.db, country, list)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, ContactList{List: list, Country: country, Contacts: matches})
+}
+
+type contactsUpcomingHandler struct {
+	db    *sql.DB
+	clock Clock
+}
+
+func NewContactsUpcomingHandler(db *sql.DB) *contactsUpcomingHandler {
+	return &contactsUpcomingHandler{db: db, clock: systemClock{}}
+}
+
+func (h *contactsUpcomingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	now, err := requestNow(h.clock, r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	from, err := parseReferenceDate(r.URL.Query().Get("date"), now)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	days, err := intParam(r.URL.Query().Get("days"), 7, 1, 366)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	list, err := requestContactList(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	matches, err := matchContacts(h.db, country, list)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, UpcomingContacts{
+		List:     list,
+		Country:  country,
+		From:     from.Format("2006-01-02"),
+		Days:     days,
+		Contacts: upcomingContacts(matches, from, days),
+	})
+}
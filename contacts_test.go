@@ -0,0 +1,155 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testVCard = "BEGIN:VCARD\r\nVERSION:3.0\r\nN:Bērziņš;Janis;;;\r\nFN:Janis Bērziņš\r\nEMAIL:janis@example.com\r\nEND:VCARD\r\n" +
+	"BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Anna Ozola\r\nEND:VCARD\r\n" +
+	"BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Xenomorph\r\nEND:VCARD\r\n"
+
+func createTestContactsDb(t *testing.T) *sql.DB {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "06-24", "Jānis")
+	insertTestNames(t, db, "07-26", "Anna")
+	insertTestNames(t, db, "12-09", "Anna")
+	return db
+}
+
+func uploadTestContacts(t *testing.T, handler http.Handler, path, contentType, body string) ContactList {
+	rr, req := setupTestRequest(t, http.MethodPost, path, []byte(body))
+	req.Header.Set("Content-Type", contentType)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+
+	var result ContactList
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	return result
+}
+
+func TestContactsHandlerUploadVCard(t *testing.T) {
+	db := createTestContactsDb(t)
+	handler := NewContactsHandler(db)
+
+	result := uploadTestContacts(t, handler, "/api/v1/contacts", "text/vcard", testVCard)
+	if result.List != DefaultContactList || len(result.Contacts) != 3 {
+		t.Fatalf("Unexpected contact list: %v", result)
+	}
+
+	janis := result.Contacts[0]
+	if janis.FirstName != "Janis" || janis.Email != "janis@example.com" || len(janis.Dates) != 1 || janis.Dates[0] != "06-24" || janis.Ambiguous {
+		t.Errorf("Expected Janis to match 06-24 without diacritics, got %v", janis)
+	}
+	anna := result.Contacts[1]
+	if len(anna.Dates) != 2 || !anna.Ambiguous {
+		t.Errorf("Expected Anna to be ambiguous, got %v", anna)
+	}
+	if unknown := result.Contacts[2]; len(unknown.Dates) != 0 || unknown.Ambiguous {
+		t.Errorf("Expected no match for Xenomorph, got %v", unknown)
+	}
+}
+
+func TestContactsHandlerUploadReplacesList(t *testing.T) {
+	db := createTestContactsDb(t)
+	handler := NewContactsHandler(db)
+
+	uploadTestContacts(t, handler, "/api/v1/contacts?list=team", "", testVCard)
+	result := uploadTestContacts(t, handler, "/api/v1/contacts?list=team", "text/csv", "first_name,last_name\nAnna,Ozola\n")
+	if len(result.Contacts) != 1 || result.Contacts[0].Name != "Anna Ozola" {
+		t.Errorf("Expected the upload to replace the list, got %v", result.Contacts)
+	}
+
+	// Other lists are left alone
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/contacts", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	if body := rr.Body.String(); body != `{"list":"default","country":"lv","contacts":[]}` {
+		t.Errorf("Expected the default list to be empty, got %s", body)
+	}
+}
+
+func TestContactsHandlerRejectsInvalidUploads(t *testing.T) {
+	db := createTestContactsDb(t)
+	handler := NewContactsHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/contacts", []byte("name,email\nAnna,anna@example.com\n,nobody@example.com\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusBadRequest)
+	if body := rr.Body.String(); !strings.Contains(body, `"line":3`) {
+		t.Errorf("Expected the invalid row to be reported, got %s", body)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodPost, "/api/v1/contacts", []byte(`{"name":"Anna"}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusBadRequest)
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/api/v1/contacts?list=no/such", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusBadRequest)
+
+	// Nothing was stored by the rejected uploads
+	matches, err := matchContacts(db, "lv", DefaultContactList)
+	if err != nil {
+		t.Fatalf("matchContacts returned an error: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("Expected no contacts, got %v", matches)
+	}
+}
+
+func TestContactsUpcomingHandler(t *testing.T) {
+	db := createTestContactsDb(t)
+	uploadTestContacts(t, NewContactsHandler(db), "/api/v1/contacts", "text/vcard", testVCard)
+	handler := NewContactsUpcomingHandler(db)
+	handler.clock = fixedClock(time.Date(2023, time.June, 20, 10, 0, 0, 0, time.UTC))
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/contacts/upcoming", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+
+	var result UpcomingContacts
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(result.Contacts) != 1 {
+		t.Fatalf("Expected only Janis in the next week, got %v", result.Contacts)
+	}
+	if janis := result.Contacts[0]; janis.Name != "Janis Bērziņš" || janis.Date != "06-24" || janis.DaysUntil != 4 || janis.Year != 2023 {
+		t.Errorf("Unexpected upcoming contact: %v", janis)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/api/v1/contacts/upcoming?days=60", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(result.Contacts) != 2 || result.Contacts[1].Date != "07-26" || !result.Contacts[1].Ambiguous {
+		t.Errorf("Expected Anna to follow Janis and be flagged ambiguous, got %v", result.Contacts)
+	}
+}
+
+func TestUpcomingContactsWrapsYear(t *testing.T) {
+	matches := []ContactMatch{
+		{ID: 1, Name: "Silvestrs", Dates: []string{"12-31"}},
+		{ID: 2, Name: "Laimnesis", Dates: []string{"01-01"}},
+		{ID: 3, Name: "Anna", Dates: []string{"07-26", "12-09"}, Ambiguous: true},
+	}
+
+	upcoming := upcomingContacts(matches, time.Date(2023, time.December, 30, 0, 0, 0, 0, time.UTC), 3)
+	if len(upcoming) != 2 {
+		t.Fatalf("Expected 2 upcoming contacts, got %v", upcoming)
+	}
+	if upcoming[0].Name != "Silvestrs" || upcoming[0].Year != 2023 || upcoming[1].Name != "Laimnesis" || upcoming[1].Year != 2024 {
+		t.Errorf("Expected contacts in chronological order across the new year, got %v", upcoming)
+	}
+}
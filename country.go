@@ -0,0 +1,158 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const DefaultCountry = "lv"
+
+// Countries maps each supported calendar, by ISO 3166 country code, to the
+// ISO 639 language used to pick it from Accept-Language. A country is only
+// listed once its dataset is bundled in data.
+var Countries = map[string]string{
+	"lv": "lv",
+}
+
+// defaultCountry is used when a request neither names a country nor sends
+// an Accept-Language header matching one
+var defaultCountry = DefaultCountry
+
+// SetDefaultCountry changes the calendar used when a request does not pick one
+func SetDefaultCountry(code string) error {
+	code = strings.ToLower(code)
+	if _, ok := Countries[code]; !ok {
+		return fmt.Errorf("unsupported country %q", code)
+	}
+	defaultCountry = code
+	return nil
+}
+
+// loadedCountries holds the countries with namedays in the database. It
+// stays nil, accepting every supported country, until LoadCountries runs.
+var (
+	loadedMu        sync.RWMutex
+	loadedCountries map[string]bool
+)
+
+// LoadCountries records which countries have namedays in db, so that
+// requests are only resolved to calendars that can answer them
+func LoadCountries(db *sql.DB) error {
+	rows, err := db.Query("SELECT DISTINCT country FROM namedays")
+	if err != nil {
+		return fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	loaded := make(map[string]bool)
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return fmt.Errorf("error scanning row: %w", err)
+		}
+		loaded[code] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	loadedMu.Lock()
+	loadedCountries = loaded
+	loadedMu.Unlock()
+	return nil
+}
+
+// markCountryLoaded records that namedays were added for code
+func markCountryLoaded(code string) {
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+	if loadedCountries != nil {
+		loadedCountries[code] = true
+	}
+}
+
+// countryLoaded reports whether namedays can be served for code
+func countryLoaded(code string) bool {
+	loadedMu.RLock()
+	defer loadedMu.RUnlock()
+	if loadedCountries == nil {
+		_, ok := Countries[code]
+		return ok
+	}
+	return loadedCountries[code]
+}
+
+// requestCountry returns the calendar requested through the country query
+// parameter, then the Accept-Language header, then the configured default.
+// Accept-Language only picks countries with namedays loaded, and naming a
+// country without any is an error.
+func requestCountry(r *http.Request) (string, error) {
+	if code := r.URL.Query().Get("country"); code != "" {
+		code = strings.ToLower(code)
+		if _, ok := Countries[code]; !ok {
+			return "", fmt.Errorf("unsupported country %q", code)
+		}
+		if !countryLoaded(code) {
+			return "", fmt.Errorf("no namedays loaded for country %q", code)
+		}
+		return code, nil
+	}
+
+	if code := countryFromAcceptLanguage(r.Header.Get("Accept-Language")); code != "" {
+		return code, nil
+	}
+
+	return defaultCountry, nil
+}
+
+type languageRange struct {
+	tag     string
+	quality float64
+}
+
+// countryFromAcceptLanguage picks the loaded country preferred by an
+// Accept-Language header, matching either the language ("lv") or the
+// region ("en-LV") of each range
+func countryFromAcceptLanguage(header string) string {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, languageRange{tag: tag, quality: quality})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+
+	for _, lr := range ranges {
+		subtags := strings.Split(lr.tag, "-")
+		for country, language := range Countries {
+			if subtags[0] == language && countryLoaded(country) {
+				return country
+			}
+		}
+		if len(subtags) > 1 && countryLoaded(subtags[len(subtags)-1]) {
+			return subtags[len(subtags)-1]
+		}
+	}
+	return ""
+}
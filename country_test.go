@@ -0,0 +1,224 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zrks/namedays/pkg/namedays"
+)
+
+// testCountries are supported by tests of several calendars
+var testCountries = map[string]string{
+	"lt": "lt",
+	"ee": "et",
+	"hu": "hu",
+	"cz": "cs",
+	"sk": "sk",
+	"pl": "pl",
+}
+
+// addTestCountries supports testCountries for the duration of a test, as if
+// their datasets were bundled
+func addTestCountries(t *testing.T) {
+	for code, language := range testCountries {
+		Countries[code] = language
+	}
+	t.Cleanup(func() {
+		for code := range testCountries {
+			delete(Countries, code)
+		}
+	})
+}
+
+func TestCountriesBundled(t *testing.T) {
+	datasets, err := LoadDatasets("")
+	if err != nil {
+		t.Fatalf("LoadDatasets returned an error: %v", err)
+	}
+	bundled := datasetCountries(datasets)
+	for code := range Countries {
+		if _, ok := bundled[code]; !ok {
+			t.Errorf("Country %s is supported without a bundled dataset", code)
+		}
+	}
+}
+
+func TestCountryFromAcceptLanguage(t *testing.T) {
+	addTestCountries(t)
+	cases := map[string]string{
+		"":                             "",
+		"lv":                           "lv",
+		"en-US,en;q=0.9":               "",
+		"en-US,lt;q=0.8,lv;q=0.9":      "lv",
+		"cs-CZ":                        "cz",
+		"et;q=0.5, hu;q=0.7":           "hu",
+		"en-PL":                        "pl",
+		"de;q=1, lv;q=0, *;q=0.1":      "",
+		"fr-FR, sk-SK;q=0.2, pl;q=0.1": "sk",
+	}
+	for header, expected := range cases {
+		if result := countryFromAcceptLanguage(header); result != expected {
+			t.Errorf("countryFromAcceptLanguage(%q) returned %q, expected %q", header, result, expected)
+		}
+	}
+}
+
+func TestRequestCountry(t *testing.T) {
+	_, req := setupTestRequest(t, http.MethodGet, "/", nil)
+	if country, err := requestCountry(req); err != nil || country != DefaultCountry {
+		t.Errorf("Expected default country, got %s (%v)", country, err)
+	}
+
+	_, req = setupTestRequest(t, http.MethodGet, "/?country=lt", nil)
+	if _, err := requestCountry(req); err == nil {
+		t.Error("Expected an error for a country without a bundled dataset")
+	}
+
+	addTestCountries(t)
+	_, req = setupTestRequest(t, http.MethodGet, "/?country=LT", nil)
+	req.Header.Set("Accept-Language", "hu")
+	if country, err := requestCountry(req); err != nil || country != "lt" {
+		t.Errorf("Expected query parameter to win, got %s (%v)", country, err)
+	}
+
+	_, req = setupTestRequest(t, http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "hu-HU,hu;q=0.9")
+	if country, err := requestCountry(req); err != nil || country != "hu" {
+		t.Errorf("Expected Accept-Language country, got %s (%v)", country, err)
+	}
+
+	_, req = setupTestRequest(t, http.MethodGet, "/?country=xx", nil)
+	if _, err := requestCountry(req); err == nil {
+		t.Error("Expected an error for an unsupported country")
+	}
+}
+
+func TestRequestCountryOnlyLoaded(t *testing.T) {
+	addTestCountries(t)
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "06-24", "Jānis")
+	if err := LoadCountries(db); err != nil {
+		t.Fatalf("LoadCountries returned an error: %v", err)
+	}
+	t.Cleanup(func() { loadedCountries = nil })
+
+	// Browsers asking for a calendar without data get the default one
+	_, req := setupTestRequest(t, http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "pl-PL,pl;q=0.9,lv;q=0.5")
+	if country, err := requestCountry(req); err != nil || country != "lv" {
+		t.Errorf("Expected the loaded lv, got %s (%v)", country, err)
+	}
+
+	// Naming one explicitly is an error rather than an empty answer
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/date/06-24?country=pl", nil)
+	NewDateHandler(db).ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusBadRequest)
+
+	// Countries become available once namedays are added for them
+	markCountryLoaded("pl")
+	_, req = setupTestRequest(t, http.MethodGet, "/?country=pl", nil)
+	if country, err := requestCountry(req); err != nil || country != "pl" {
+		t.Errorf("Expected pl after it was loaded, got %s (%v)", country, err)
+	}
+}
+
+func TestSetDefaultCountry(t *testing.T) {
+	addTestCountries(t)
+	t.Cleanup(func() { defaultCountry = DefaultCountry })
+
+	if err := SetDefaultCountry("EE"); err != nil || defaultCountry != "ee" {
+		t.Errorf("Expected default country ee, got %s (%v)", defaultCountry, err)
+	}
+	if err := SetDefaultCountry("xx"); err == nil {
+		t.Error("Expected an error for an unsupported country")
+	}
+}
+
+func TestDateHandlerCountry(t *testing.T) {
+	addTestCountries(t)
+	_, db := createTestDb(t)
+	store := namedays.NewSQLStore(db, defaultCountry)
+	store.Add("janis", namedays.Nameday{Name: "Jānis", Date: "06-24", Country: "lv"})
+	store.Add("jonas", namedays.Nameday{Name: "Jonas", Date: "06-24", Country: "lt"})
+	handler := NewDateHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/date/06-24?country=lt", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	result := decodeDateNamedays(t, rr.Body.Bytes())
+	if result.Country != "lt" || len(result.Names) != 1 || result.Names[0] != "Jonas" {
+		t.Errorf("Expected Lithuanian namedays, got %v", result)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/api/v1/date/06-24?country=zz", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusBadRequest)
+}
+
+func TestNamedayHandlerListNamedaysByCountry(t *testing.T) {
+	addTestCountries(t)
+	store, handler := createTestNamedayHandler()
+	store.Add("janis", namedays.Nameday{Name: "Jānis", Date: "06-24"})
+	store.Add("jonas", namedays.Nameday{Name: "Jonas", Date: "06-24", Country: "lt"})
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/nameday?country=lt", nil)
+	handler.ServeHTTP(rr, req)
+
+	var responseNamedays map[string]namedays.Nameday
+	if err := json.Unmarshal(rr.Body.Bytes(), &responseNamedays); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if _, exists := responseNamedays["jonas"]; len(responseNamedays) != 1 || !exists {
+		t.Errorf("Expected only jonas, got %v", responseNamedays)
+	}
+}
+
+func TestInitDBLoadsEveryCountry(t *testing.T) {
+	addTestCountries(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"lv.json":      `{"06-24": ["Jānis"]}`,
+		"lt.json":      `{"06-24": ["Jonas", "Janas"]}`,
+		"unknown.json": `{"06-24": ["Nobody"]}`,
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			t.Fatal("Failed to write dataset:", err)
+		}
+	}
+
+	datasets, err := FindDatasets(os.DirFS(dir))
+	if err != nil {
+		t.Fatalf("FindDatasets returned an error: %v", err)
+	}
+
+	dbPath := filepath.Join(dir, "namedays.db")
+	// Running twice must not load the datasets again
+	for i := 0; i < 2; i++ {
+		if err := InitDB(dbPath, datasets); err != nil {
+			t.Fatalf("InitDB returned an error: %v", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal("Failed to open database:", err)
+	}
+	defer db.Close()
+
+	expected := map[string]int{"lv": 1, "lt": 2}
+	for country, count := range expected {
+		names, err := getNamedaysForDate(db, country, "06-24")
+		if err != nil {
+			t.Fatalf("getNamedaysForDate returned an error: %v", err)
+		}
+		if len(names) != count {
+			t.Errorf("Expected %d %s names, got %v", count, country, names)
+		}
+	}
+}
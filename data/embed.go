@@ -0,0 +1,8 @@
+// Package data bundles the default nameday datasets into the binary, one
+// <country>.json file per calendar mapping "MM-DD" dates to names.
+package data
+
+import "embed"
+
+//go:embed *.json
+var FS embed.FS
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zrks/namedays/data"
+)
+
+// Dataset is a JSON file holding one country's namedays. Override marks
+// datasets given with -data, which are applied on every start.
+type Dataset struct {
+	Country  string
+	FS       fs.FS
+	Name     string
+	Override bool
+}
+
+// FindDatasets lists the <country>.json files of fsys for supported countries
+func FindDatasets(fsys fs.FS) ([]Dataset, error) {
+	matches, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list datasets: %w", err)
+	}
+
+	var datasets []Dataset
+	for _, name := range matches {
+		country := strings.TrimSuffix(path.Base(name), ".json")
+		if _, ok := Countries[country]; !ok {
+			continue
+		}
+		datasets = append(datasets, Dataset{Country: country, FS: fsys, Name: name})
+	}
+	return datasets, nil
+}
+
+// LoadDatasets returns the datasets embedded in the binary, with override
+// taking precedence when set. The override is either a directory of
+// <country>.json files or a single file, which is used for the country
+// it is named after or else for the default country.
+func LoadDatasets(override string) ([]Dataset, error) {
+	bundled, err := FindDatasets(data.FS)
+	if err != nil {
+		return nil, err
+	}
+	if override == "" {
+		return bundled, nil
+	}
+
+	info, err := os.Stat(override)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+
+	var external []Dataset
+	if info.IsDir() {
+		if external, err = FindDatasets(os.DirFS(override)); err != nil {
+			return nil, err
+		}
+	} else {
+		country := strings.TrimSuffix(filepath.Base(override), filepath.Ext(override))
+		if _, ok := Countries[country]; !ok {
+			country = defaultCountry
+		}
+		external = []Dataset{{Country: country, FS: os.DirFS(filepath.Dir(override)), Name: filepath.Base(override)}}
+	}
+
+	byCountry := make(map[string]Dataset)
+	for _, ds := range bundled {
+		byCountry[ds.Country] = ds
+	}
+	for _, ds := range external {
+		ds.Override = true
+		byCountry[ds.Country] = ds
+	}
+
+	datasets := make([]Dataset, 0, len(byCountry))
+	for _, ds := range byCountry {
+		datasets = append(datasets, ds)
+	}
+	sort.Slice(datasets, func(i, j int) bool { return datasets[i].Country < datasets[j].Country })
+	return datasets, nil
+}
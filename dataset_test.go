@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestDataset(t *testing.T, dir, file, content string) string {
+	path := filepath.Join(dir, file)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal("Failed to write dataset:", err)
+	}
+	return path
+}
+
+func datasetCountries(datasets []Dataset) map[string]Dataset {
+	result := make(map[string]Dataset)
+	for _, ds := range datasets {
+		result[ds.Country] = ds
+	}
+	return result
+}
+
+func TestLoadDatasetsBundled(t *testing.T) {
+	datasets, err := LoadDatasets("")
+	if err != nil {
+		t.Fatalf("LoadDatasets returned an error: %v", err)
+	}
+
+	lv, ok := datasetCountries(datasets)["lv"]
+	if !ok {
+		t.Fatal("Expected the Latvian dataset to be embedded")
+	}
+
+	// The embedded data must be usable without any file on disk
+	dbPath := filepath.Join(t.TempDir(), "namedays.db")
+	if err := InitDB(dbPath, []Dataset{lv}); err != nil {
+		t.Fatalf("InitDB returned an error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal("Failed to open database:", err)
+	}
+	defer db.Close()
+
+	names, err := getNamedaysForDate(db, "lv", "06-24")
+	if err != nil {
+		t.Fatalf("getNamedaysForDate returned an error: %v", err)
+	}
+	if len(names) == 0 {
+		t.Error("Expected namedays on 06-24")
+	}
+}
+
+func TestLoadDatasetsOverrideFile(t *testing.T) {
+	path := writeTestDataset(t, t.TempDir(), "custom.json", `{"06-24": ["Custom"]}`)
+
+	datasets, err := LoadDatasets(path)
+	if err != nil {
+		t.Fatalf("LoadDatasets returned an error: %v", err)
+	}
+
+	lv := datasetCountries(datasets)["lv"]
+	if lv.Name != "custom.json" {
+		t.Errorf("Expected custom.json to replace the default country, got %s", lv.Name)
+	}
+}
+
+func TestLoadDatasetsOverrideDirectory(t *testing.T) {
+	addTestCountries(t)
+	dir := t.TempDir()
+	writeTestDataset(t, dir, "lt.json", `{"06-24": ["Jonas"]}`)
+
+	datasets, err := LoadDatasets(dir)
+	if err != nil {
+		t.Fatalf("LoadDatasets returned an error: %v", err)
+	}
+
+	countries := datasetCountries(datasets)
+	if _, ok := countries["lt"]; !ok {
+		t.Error("Expected the external Lithuanian dataset")
+	}
+	if lv, ok := countries["lv"]; !ok || lv.Name != "lv.json" {
+		t.Error("Expected the bundled Latvian dataset to be kept")
+	}
+}
+
+func TestLoadDatasetsMissingOverride(t *testing.T) {
+	if _, err := LoadDatasets(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing dataset")
+	}
+}
+
+func TestInitDBAppliesOverrideToPopulatedDatabase(t *testing.T) {
+	bundled, err := LoadDatasets("")
+	if err != nil {
+		t.Fatalf("LoadDatasets returned an error: %v", err)
+	}
+	dbPath := filepath.Join(t.TempDir(), "namedays.db")
+	if err := InitDB(dbPath, bundled); err != nil {
+		t.Fatalf("InitDB returned an error: %v", err)
+	}
+
+	// Restarting with -data must pick up the override even though the
+	// country already has rows
+	path := writeTestDataset(t, t.TempDir(), "lv.json", `{"06-24": ["Custom"]}`)
+	datasets, err := LoadDatasets(path)
+	if err != nil {
+		t.Fatalf("LoadDatasets returned an error: %v", err)
+	}
+	if lv := datasetCountries(datasets)["lv"]; !lv.Override {
+		t.Fatal("Expected the lv dataset to be marked as an override")
+	}
+	if err := InitDB(dbPath, datasets); err != nil {
+		t.Fatalf("InitDB returned an error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal("Failed to open database:", err)
+	}
+	defer db.Close()
+
+	names, err := getNamedaysForDate(db, "lv", "06-24")
+	if err != nil {
+		t.Fatalf("getNamedaysForDate returned an error: %v", err)
+	}
+	found := false
+	for _, name := range names {
+		found = found || name == "Custom"
+	}
+	if !found {
+		t.Errorf("Expected Custom on 06-24, got %v", names)
+	}
+}
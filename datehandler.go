@@ -0,0 +1,125 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+var (
+	DateRe      = regexp.MustCompile(`^/api/v1/date/([^/]+)$`)
+	MonthDayRe  = regexp.MustCompile(`^\d{2}-\d{2}$`)
+	relativeDay = map[string]int{"yesterday": -1, "today": 0, "tomorrow": 1}
+)
+
+// DateNamedays is the JSON representation of the names celebrated on a date
+type DateNamedays struct {
+	Country string   `json:"country"`
+	Date    string   `json:"date"`
+	Names   []string `json:"names"`
+}
+
+// ParseMonthDay validates an "MM-DD" string. Days are checked against a leap
+// year so that 02-29 is always accepted.
+func ParseMonthDay(s string) (string, error) {
+	if !MonthDayRe.MatchString(s) {
+		return "", fmt.Errorf("invalid date %q: expected MM-DD", s)
+	}
+	if _, err := time.Parse("2006-01-02", "2000-"+s); err != nil {
+		return "", fmt.Errorf("invalid date %q: no such day", s)
+	}
+	return s, nil
+}
+
+// resolveDate turns either an "MM-DD" string or one of today, tomorrow and
+// yesterday into an "MM-DD" string relative to now
+func resolveDate(s string, now time.Time) (string, error) {
+	if offset, ok := relativeDay[s]; ok {
+		return now.AddDate(0, 0, offset).Format("01-02"), nil
+	}
+	return ParseMonthDay(s)
+}
+
+// getNamedaysForDate returns the names celebrated on the given "MM-DD" date
+// in a country's calendar
+func getNamedaysForDate(db *sql.DB, country, date string) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM namedays WHERE country = ? AND date = ? ORDER BY id", country, date)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		names = append(names, name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return names, nil
+}
+
+type dateHandler struct {
+	db    *sql.DB
+	clock Clock
+}
+
+func NewDateHandler(db *sql.DB) *dateHandler {
+	return &dateHandler{db: db, clock: systemClock{}}
+}
+
+func (h *dateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	matches := DateRe.FindStringSubmatch(r.URL.Path)
+	if r.Method != http.MethodGet || len(matches) < 2 {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	now, err := requestNow(h.clock, r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	date, err := resolveDate(matches[1], now)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	names, err := getNamedaysForDate(h.db, country, date)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, DateNamedays{Country: country, Date: date, Names: names})
+}
+
+// writeJSON marshals v and writes it with the given status code
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jsonBytes)
+}
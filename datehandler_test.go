@@ -0,0 +1,133 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gosimple/slug"
+)
+
+func insertTestNames(t *testing.T, db *sql.DB, date string, names ...string) {
+	for _, name := range names {
+		if _, err := db.Exec("INSERT INTO namedays (date, name, slug, country) VALUES (?, ?, ?, ?)", date, name, slug.Make(name), defaultCountry); err != nil {
+			t.Fatal("Failed to insert test data:", err)
+		}
+	}
+}
+
+func decodeDateNamedays(t *testing.T, body []byte) DateNamedays {
+	var result DateNamedays
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	return result
+}
+
+func TestParseMonthDay(t *testing.T) {
+	valid := []string{"01-01", "02-29", "04-12", "12-31"}
+	for _, s := range valid {
+		if _, err := ParseMonthDay(s); err != nil {
+			t.Errorf("ParseMonthDay(%q) returned an error: %v", s, err)
+		}
+	}
+
+	invalid := []string{"13-40", "02-30", "04-31", "00-10", "4-12", "04-12-2024", "banana", ""}
+	for _, s := range invalid {
+		if _, err := ParseMonthDay(s); err == nil {
+			t.Errorf("ParseMonthDay(%q) expected an error", s)
+		}
+	}
+}
+
+func TestResolveDate(t *testing.T) {
+	now := time.Date(2023, time.February, 28, 12, 0, 0, 0, time.UTC)
+	cases := map[string]string{
+		"today":     "02-28",
+		"tomorrow":  "03-01",
+		"yesterday": "02-27",
+		"02-29":     "02-29",
+	}
+	for input, expected := range cases {
+		result, err := resolveDate(input, now)
+		if err != nil {
+			t.Errorf("resolveDate(%q) returned an error: %v", input, err)
+		}
+		if result != expected {
+			t.Errorf("resolveDate(%q) returned %s, expected %s", input, result, expected)
+		}
+	}
+}
+
+func TestDateHandler(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "04-12", "Jūlijs", "Ainis")
+	handler := NewDateHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/date/04-12", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got %s", ct)
+	}
+
+	result := decodeDateNamedays(t, rr.Body.Bytes())
+	if result.Date != "04-12" {
+		t.Errorf("Expected date 04-12, got %s", result.Date)
+	}
+	if len(result.Names) != 2 || result.Names[0] != "Jūlijs" || result.Names[1] != "Ainis" {
+		t.Errorf("Unexpected names: %v", result.Names)
+	}
+}
+
+func TestDateHandlerToday(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "06-24", "Test Name")
+	handler := NewDateHandler(db)
+	handler.clock = fixedClock(midsummer)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/date/today", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	result := decodeDateNamedays(t, rr.Body.Bytes())
+	if result.Date != "06-24" || len(result.Names) != 1 {
+		t.Errorf("Unexpected response for today: %v", result)
+	}
+}
+
+func TestDateHandlerEmptyDate(t *testing.T) {
+	_, db := createTestDb(t)
+	handler := NewDateHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/date/02-29", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	if body := rr.Body.String(); body != `{"country":"lv","date":"02-29","names":[]}` {
+		t.Errorf("Unexpected body: %s", body)
+	}
+}
+
+func TestDateHandlerInvalidDate(t *testing.T) {
+	_, db := createTestDb(t)
+	handler := NewDateHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/date/13-40", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusBadRequest)
+}
+
+func TestDateHandlerInvalidMethod(t *testing.T) {
+	_, db := createTestDb(t)
+	handler := NewDateHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/date/04-12", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusNotFound)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zrks/namedays/pkg/importer"
+)
+
+// datasetFormat returns the explicit format, or guesses it from the file
+// extension, falling back to JSON
+func datasetFormat(format, path string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".tsv", ".tab":
+		return "tsv"
+	}
+	return "json"
+}
+
+func formatSeparator(format string) rune {
+	if format == "tsv" {
+		return '\t'
+	}
+	return ','
+}
+
+// ReadRecords reads a CSV or TSV dataset. Row errors are reported together.
+func ReadRecords(path, format string) ([]importer.Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return importer.ReadCSV(file, formatSeparator(format))
+}
+
+// runExport implements the export command:
+//
+//	db-ops export [-db path] [-format csv|tsv|json] [-country lv] [-out file]
+//
+// CSV and TSV export every country unless one is given. JSON holds a single
+// country and defaults to lv.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dbPath := fs.String("db", "./namedays.db", "path to the SQLite database")
+	format := fs.String("format", "csv", "output format: csv, tsv or json")
+	country := fs.String("country", "", "only export this country")
+	outPath := fs.String("out", "", "write to this file instead of standard output")
+	fs.Parse(args)
+
+	db, err := sql.Open("sqlite3", *dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if *format == "json" && *country == "" {
+		*country = "lv"
+	}
+
+	records, err := importer.ExportRecords(db, *country)
+	if err != nil {
+		log.Fatal("Failed to read data:", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	switch *format {
+	case "csv", "tsv":
+		err = importer.WriteCSV(out, records, formatSeparator(*format))
+	case "json":
+		namedayData := make(importer.Namedays)
+		for _, r := range records {
+			namedayData[r.Date] = append(namedayData[r.Date], r.Name)
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "    ")
+		encoder.SetEscapeHTML(false)
+		err = encoder.Encode(namedayData)
+	default:
+		log.Fatalf("Unknown export format %q (want csv, tsv or json)", *format)
+	}
+	if err != nil {
+		log.Fatal("Failed to write export:", err)
+	}
+}
@@ -2,4 +2,14 @@ module db-ops
 
 go 1.24.1
 
-require github.com/mattn/go-sqlite3 v1.14.24
+require (
+	github.com/mattn/go-sqlite3 v1.14.24
+	github.com/zrks/namedays v0.0.0
+)
+
+require (
+	github.com/gosimple/slug v1.15.0 // indirect
+	github.com/gosimple/unidecode v1.0.1 // indirect
+)
+
+replace github.com/zrks/namedays => ../
@@ -1,97 +1,102 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 
 	_ "github.com/mattn/go-sqlite3"
-)
 
-// Nameday represents a mapping of date to names
-type Nameday map[string][]string
+	"github.com/zrks/namedays/data"
+	"github.com/zrks/namedays/pkg/importer"
+	"github.com/zrks/namedays/pkg/migrations"
+)
 
-// InitializeDatabase creates the database and the namedays table
+// InitializeDatabase brings the database schema up to the latest migration
 func InitializeDatabase(db *sql.DB) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS namedays (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		date TEXT NOT NULL,
-		name TEXT NOT NULL
-	);
-	`
-	_, err := db.Exec(query)
-	return err
+	return migrations.Up(db)
 }
 
-// InsertNamedays inserts parsed nameday data into SQLite
-func InsertNamedays(db *sql.DB, namedayData Nameday) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return err
+// ReadNamedays reads a JSON dataset, defaulting to the one bundled for the
+// country when path is empty
+func ReadNamedays(path, country string) (importer.Namedays, error) {
+	var jsonData []byte
+	var err error
+	if path == "" {
+		jsonData, err = data.FS.ReadFile(country + ".json")
+	} else {
+		jsonData, err = os.ReadFile(path)
 	}
-	stmt, err := tx.Prepare("INSERT INTO namedays (date, name) VALUES (?, ?)")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer stmt.Close()
 
-	for date, names := range namedayData {
-		for _, name := range names {
-			_, err = stmt.Exec(date, name)
-			if err != nil {
-				tx.Rollback()
-				return err
-			}
-		}
+	var namedayData importer.Namedays
+	if err = json.Unmarshal(jsonData, &namedayData); err != nil {
+		return nil, err
 	}
-
-	return tx.Commit()
+	return namedayData, nil
 }
 
-// QueryNamedays prints all records in the namedays table
-func QueryNamedays(db *sql.DB) {
-	rows, err := db.Query("SELECT date, name FROM namedays ORDER BY date")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var date, name string
-		if err := rows.Scan(&date, &name); err != nil {
-			log.Fatal(err)
+// runImport implements the import command, which is also the default:
+//
+//	db-ops [import] [-db path] [-file dataset | -from-url URL] [-format json|csv|tsv] [-country lv] [-dry-run] [-prune]
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dataPath := fs.String("file", "", "path to the dataset (defaults to the bundled one)")
+	format := fs.String("format", "", "dataset format: json, csv or tsv (defaults to the file extension)")
+	country := fs.String("country", "lv", "country code of the dataset")
+	dbPath := fs.String("db", "./namedays.db", "path to the SQLite database")
+	dryRun := fs.Bool("dry-run", false, "print the changes without applying them")
+	prune := fs.Bool("prune", false, "delete names that are no longer in the dataset (names added through the API are kept)")
+	fromURL := fs.String("from-url", "", "download the JSON dataset from this URL instead of reading a file")
+	checksum := fs.String("sha256", "", "expected SHA-256 checksum of the downloaded dataset")
+	timeout := fs.Duration("timeout", importer.DefaultFetchTimeout, "timeout for downloading the dataset")
+	maxBytes := fs.Int64("max-bytes", importer.DefaultMaxBytes, "maximum size of the downloaded dataset")
+	statePath := fs.String("state", "", "file caching ETag and Last-Modified between downloads")
+	fs.Parse(args)
+
+	var fetcher *importer.Fetcher
+	var namedayData importer.Namedays
+	var records []importer.Record
+	var err error
+	if *fromURL != "" {
+		fetcher = importer.NewFetcher()
+		fetcher.Client.Timeout = *timeout
+		fetcher.MaxBytes = *maxBytes
+		fetcher.SHA256 = *checksum
+		if *statePath != "" {
+			if fetcher.State, err = importer.LoadFetchState(*statePath); err != nil {
+				log.Fatal(err)
+			}
 		}
-		fmt.Printf("Date: %s, Name: %s\n", date, name)
-	}
-}
-
-func main() {
-	// Open JSON file
-	file, err := os.Open("namedays.json")
-	if err != nil {
-		log.Fatal("Error opening JSON file:", err)
-	}
-	defer file.Close()
-
-	// Read JSON file
-	jsonData, err := io.ReadAll(file)
-	if err != nil {
-		log.Fatal("Error reading JSON file:", err)
-	}
 
-	// Parse JSON
-	var namedayData Nameday
-	err = json.Unmarshal(jsonData, &namedayData)
-	if err != nil {
-		log.Fatal("Error parsing JSON:", err)
+		namedayData, err = fetcher.Fetch(context.Background(), *fromURL)
+		if err != nil {
+			log.Fatal("Error downloading JSON dataset:", err)
+		}
+		if namedayData == nil {
+			fmt.Println("Dataset not modified since the last import")
+			return
+		}
+	} else if f := datasetFormat(*format, *dataPath); f == "csv" || f == "tsv" {
+		records, err = ReadRecords(*dataPath, f)
+		if err != nil {
+			log.Fatalf("Error reading %s dataset:\n%v", f, err)
+		}
+	} else {
+		namedayData, err = ReadNamedays(*dataPath, *country)
+		if err != nil {
+			log.Fatal("Error reading JSON dataset:", err)
+		}
 	}
 
 	// Initialize SQLite database
-	db, err := sql.Open("sqlite3", "./namedays.db")
+	db, err := sql.Open("sqlite3", *dbPath)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -102,13 +107,49 @@ func main() {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
-	// Insert data
-	err = InsertNamedays(db, namedayData)
+	// Upsert data and report what changed
+	opts := importer.Options{
+		Country: *country,
+		Prune:   *prune,
+		DryRun:  *dryRun,
+	}
+	var reports []*importer.Report
+	if records != nil {
+		reports, err = importer.ImportRecords(db, records, opts)
+	} else {
+		var report *importer.Report
+		report, err = importer.Import(db, namedayData, opts)
+		reports = append(reports, report)
+	}
 	if err != nil {
-		log.Fatal("Failed to insert data:", err)
+		log.Fatal("Failed to import data:", err)
+	}
+	for _, report := range reports {
+		report.Print(os.Stdout)
 	}
 
-	// Query and display stored data
-	fmt.Println("Namedays in database:")
-	QueryNamedays(db)
+	if fetcher != nil && *statePath != "" && !*dryRun {
+		if err = fetcher.State.Save(*statePath); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func main() {
+	args := os.Args[1:]
+	command := "import"
+	if len(args) > 0 && (args[0] == "migrate" || args[0] == "import" || args[0] == "validate" || args[0] == "export") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "migrate":
+		runMigrate(args)
+	case "validate":
+		runValidate(args)
+	case "export":
+		runExport(args)
+	default:
+		runImport(args)
+	}
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/zrks/namedays/pkg/migrations"
+)
+
+// runMigrate implements the migrate command:
+//
+//	db-ops migrate [-db path] up
+//	db-ops migrate [-db path] down [steps]
+//	db-ops migrate [-db path] to <version>
+//	db-ops migrate [-db path] status
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dbPath := fs.String("db", "./namedays.db", "path to the SQLite database")
+	fs.Parse(args)
+
+	db, err := sql.Open("sqlite3", *dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	action := fs.Arg(0)
+	if action == "" {
+		action = "up"
+	}
+
+	switch action {
+	case "up":
+		err = migrations.Up(db)
+	case "down":
+		steps := 1
+		if fs.Arg(1) != "" {
+			if steps, err = strconv.Atoi(fs.Arg(1)); err != nil {
+				log.Fatal("Invalid number of steps:", err)
+			}
+		}
+		err = migrations.Down(db, steps)
+	case "to":
+		version, convErr := strconv.Atoi(fs.Arg(1))
+		if convErr != nil {
+			log.Fatal("Invalid target version:", convErr)
+		}
+		err = migrations.Migrate(db, version)
+	case "status":
+	default:
+		log.Fatalf("Unknown migrate action %q (want up, down, to or status)", action)
+	}
+	if err != nil {
+		log.Fatal("Migration failed:", err)
+	}
+
+	statuses, err := migrations.List(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, s := range statuses {
+		state := "pending"
+		if s.Applied {
+			state = "applied " + s.AppliedAt
+		}
+		fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+	}
+}
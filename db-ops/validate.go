@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/zrks/namedays/pkg/importer"
+)
+
+// runValidate implements the validate command. It prints a JSON report and
+// exits with status 1 when the dataset has errors, or warnings with -strict.
+//
+//	db-ops validate [-file dataset.json] [-country lv] [-strict] [-fixed cleaned.json]
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	dataPath := fs.String("file", "", "path to the JSON dataset (defaults to the bundled one)")
+	country := fs.String("country", "lv", "country code of the bundled dataset")
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	fixedPath := fs.String("fixed", "", "write a cleaned copy of the dataset to this path")
+	fs.Parse(args)
+
+	namedayData, err := ReadNamedays(*dataPath, *country)
+	if err != nil {
+		log.Fatal("Error reading JSON dataset:", err)
+	}
+
+	report := importer.Validate(namedayData)
+	if *strict && report.Warnings > 0 {
+		report.Valid = false
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err = encoder.Encode(report); err != nil {
+		log.Fatal("Error writing report:", err)
+	}
+
+	if *fixedPath != "" {
+		fixed, err := json.MarshalIndent(importer.Clean(namedayData), "", "    ")
+		if err != nil {
+			log.Fatal("Error encoding cleaned dataset:", err)
+		}
+		if err = os.WriteFile(*fixedPath, append(fixed, '\n'), 0o644); err != nil {
+			log.Fatal("Error writing cleaned dataset:", err)
+		}
+	}
+
+	if !report.Valid {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+
+	DefaultDigestTime = "07:00"
+	DefaultBaseURL    = "http://localhost:8080"
+)
+
+var (
+	SubscriberNotFoundErr = errors.New("subscriber not found")
+
+	emailFuncs = map[string]interface{}{"join": strings.Join}
+	emailText  = map[string]*texttemplate.Template{
+		"confirm": texttemplate.Must(texttemplate.New("confirm.txt").Funcs(emailFuncs).ParseFS(templateFS, "templates/email/confirm.txt")),
+		"digest":  texttemplate.Must(texttemplate.New("digest.txt").Funcs(emailFuncs).ParseFS(templateFS, "templates/email/digest.txt")),
+	}
+	emailHTML = map[string]*htmltemplate.Template{
+		"confirm": htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/email/confirm.html")),
+		"digest":  htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/email/digest.html")),
+	}
+)
+
+// Subscriber is an email address receiving the nameday digest. Digests
+// are only sent once the address is confirmed through the emailed link.
+type Subscriber struct {
+	ID        int64  `json:"id"`
+	Email     string `json:"email"`
+	Country   string `json:"country"`
+	Frequency string `json:"frequency"`
+	Confirmed bool   `json:"confirmed"`
+	CreatedAt string `json:"created_at"`
+	Token     string `json:"-"`
+	LastSent  string `json:"-"`
+}
+
+// digestDay is one day of a digest email
+type digestDay struct {
+	Label string
+	Names []string
+}
+
+// subscriptionRequest is the body accepted when subscribing
+type subscriptionRequest struct {
+	Email     string `json:"email"`
+	Country   string `json:"country"`
+	Frequency string `json:"frequency"`
+}
+
+type confirmEmail struct {
+	Email     string
+	Country   string
+	Frequency string
+	Link      string
+}
+
+type digestEmail struct {
+	Title string
+	Days  []digestDay
+	Link  string
+}
+
+// validateSubscriber checks a subscription request and fills in defaults
+func validateSubscriber(sub *Subscriber) error {
+	addr, err := mail.ParseAddress(sub.Email)
+	if err != nil || addr.Address != sub.Email || addr.Name != "" {
+		return fmt.Errorf("invalid email address %q", sub.Email)
+	}
+
+	sub.Country = strings.ToLower(sub.Country)
+	if sub.Country == "" {
+		sub.Country = defaultCountry
+	}
+	if _, ok := Countries[sub.Country]; !ok {
+		return fmt.Errorf("unsupported country %q", sub.Country)
+	}
+
+	if sub.Frequency == "" {
+		sub.Frequency = FrequencyDaily
+	}
+	if sub.Frequency != FrequencyDaily && sub.Frequency != FrequencyWeekly {
+		return fmt.Errorf("invalid frequency %q: expected %s or %s", sub.Frequency, FrequencyDaily, FrequencyWeekly)
+	}
+	return nil
+}
+
+// saveSubscriber subscribes an address. Subscribing an unconfirmed address
+// again replaces its preferences and token. A confirmed subscription is
+// left as it is, so anyone knowing the address cannot unconfirm it, and sub
+// is filled in with the stored subscription.
+func saveSubscriber(db *sql.DB, sub *Subscriber) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(`INSERT INTO subscribers (email, country, frequency, token, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (email) DO UPDATE SET country = excluded.country, frequency = excluded.frequency, token = excluded.token
+		WHERE confirmed = 0`,
+		sub.Email, sub.Country, sub.Frequency, token, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("failed to save subscriber: %w", err)
+	}
+
+	return db.QueryRow("SELECT id, country, frequency, confirmed, created_at, token FROM subscribers WHERE email = ?", sub.Email).
+		Scan(&sub.ID, &sub.Country, &sub.Frequency, &sub.Confirmed, &sub.CreatedAt, &sub.Token)
+}
+
+func subscriberByToken(db *sql.DB, token string) (Subscriber, error) {
+	var sub Subscriber
+	err := db.QueryRow("SELECT id, email, country, frequency, confirmed, created_at, token, last_sent FROM subscribers WHERE token = ?", token).
+		Scan(&sub.ID, &sub.Email, &sub.Country, &sub.Frequency, &sub.Confirmed, &sub.CreatedAt, &sub.Token, &sub.LastSent)
+	if err == sql.ErrNoRows {
+		return Subscriber{}, SubscriberNotFoundErr
+	}
+	if err != nil {
+		return Subscriber{}, fmt.Errorf("error querying database: %w", err)
+	}
+	return sub, nil
+}
+
+// updateSubscribers runs a statement against the subscriber with token,
+// reporting SubscriberNotFoundErr when there is none
+func updateSubscribers(db *sql.DB, query, token string) error {
+	result, err := db.Exec(query, token)
+	if err != nil {
+		return fmt.Errorf("failed to update subscriber: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return SubscriberNotFoundErr
+	}
+	return nil
+}
+
+func confirmSubscriber(db *sql.DB, token string) error {
+	return updateSubscribers(db, "UPDATE subscribers SET confirmed = 1 WHERE token = ?", token)
+}
+
+func unsubscribe(db *sql.DB, token string) error {
+	return updateSubscribers(db, "DELETE FROM subscribers WHERE token = ?", token)
+}
+
+// dueSubscribers returns the confirmed subscribers who have not received
+// the digest of the day yet. Weekly digests go out on Mondays.
+func dueSubscribers(db *sql.DB, now time.Time) ([]Subscriber, error) {
+	rows, err := db.Query(`SELECT id, email, country, frequency, confirmed, created_at, token, last_sent FROM subscribers
+		WHERE confirmed = 1 AND last_sent <> ? AND (frequency = ? OR (frequency = ? AND ?)) ORDER BY id`,
+		now.Format("2006-01-02"), FrequencyDaily, FrequencyWeekly, now.Weekday() == time.Monday)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []Subscriber
+	for rows.Next() {
+		var sub Subscriber
+		if err := rows.Scan(&sub.ID, &sub.Email, &sub.Country, &sub.Frequency, &sub.Confirmed, &sub.CreatedAt, &sub.Token, &sub.LastSent); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return subs, nil
+}
+
+// renderEmail executes the plain text and HTML templates of an email
+func renderEmail(name string, data interface{}) (text, html []byte, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+	if err = emailText[name].Execute(&textBuf, data); err != nil {
+		return nil, nil, fmt.Errorf("failed to render %s email: %w", name, err)
+	}
+	if err = emailHTML[name].Execute(&htmlBuf, data); err != nil {
+		return nil, nil, fmt.Errorf("failed to render %s email: %w", name, err)
+	}
+	return textBuf.Bytes(), htmlBuf.Bytes(), nil
+}
+
+// digestMailer emails the nameday digest to confirmed subscribers at a
+// fixed local time every day
+type digestMailer struct {
+	db       *sql.DB
+	mailer   *Mailer
+	baseURL  string
+	clock    Clock
+	schedule dailySchedule
+}
+
+func NewDigestMailer(db *sql.DB, mailer *Mailer, baseURL, at string) (*digestMailer, error) {
+	schedule, err := parseSchedule(at)
+	if err != nil {
+		return nil, err
+	}
+	return &digestMailer{
+		db:       db,
+		mailer:   mailer,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		clock:    systemClock{},
+		schedule: schedule,
+	}, nil
+}
+
+// link builds an absolute subscription link carrying a subscriber's token
+func (d *digestMailer) link(path, token string) string {
+	return d.baseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// SendConfirmation emails the link that confirms a subscription
+func (d *digestMailer) SendConfirmation(sub Subscriber) error {
+	text, html, err := renderEmail("confirm", confirmEmail{
+		Email:     sub.Email,
+		Country:   strings.ToUpper(sub.Country),
+		Frequency: sub.Frequency,
+		Link:      d.link("/subscriptions/confirm", sub.Token),
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.mailer.Send(Email{
+		To:      sub.Email,
+		Subject: "Confirm your nameday digest",
+		Text:    text,
+		HTML:    html,
+	})
+}
+
+// SendDigest emails a subscriber the namedays of the day of now, or of the
+// week starting then for weekly digests
+func (d *digestMailer) SendDigest(sub Subscriber, now time.Time) error {
+	days := 1
+	title := "Namedays on " + now.Format("Monday, 2 January")
+	if sub.Frequency == FrequencyWeekly {
+		days = 7
+		title = "Namedays in the week of " + now.Format("2 January")
+	}
+
+	upcoming, err := getUpcomingNamedays(d.db, sub.Country, now, days)
+	if err != nil {
+		return err
+	}
+	byDate := make(map[string][]string, len(upcoming))
+	for _, u := range upcoming {
+		byDate[u.Date] = u.Names
+	}
+
+	data := digestEmail{Title: title, Link: d.link("/subscriptions/unsubscribe", sub.Token)}
+	for i := 0; i < days; i++ {
+		day := now.AddDate(0, 0, i)
+		data.Days = append(data.Days, digestDay{Label: day.Format("Monday, 2 January"), Names: byDate[day.Format("01-02")]})
+	}
+
+	text, html, err := renderEmail("digest", data)
+	if err != nil {
+		return err
+	}
+
+	return d.mailer.Send(Email{
+		To:      sub.Email,
+		Subject: title,
+		Text:    text,
+		HTML:    html,
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + data.Link + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+}
+
+// RunOnce sends the digest of the day of now to every subscriber due one
+func (d *digestMailer) RunOnce(ctx context.Context, now time.Time) {
+	subs, err := dueSubscribers(d.db, now)
+	if err != nil {
+		log.Printf("Digest delivery failed: %v", err)
+		return
+	}
+
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err = d.SendDigest(sub, now); err != nil {
+			log.Printf("Digest delivery to %s failed: %v", sub.Email, err)
+			continue
+		}
+		if _, err = d.db.Exec("UPDATE subscribers SET last_sent = ? WHERE id = ?", now.Format("2006-01-02"), sub.ID); err != nil {
+			log.Printf("Digest delivery to %s failed: %v", sub.Email, err)
+		}
+	}
+}
+
+// Run sends the digests at the configured local time every day until ctx
+// is cancelled
+func (d *digestMailer) Run(ctx context.Context) {
+	d.schedule.run(ctx, d.clock, d.RunOnce)
+}
+
+// subscriptionPage is a message about a subscription. With a Token it asks
+// to confirm unsubscribing it.
+type subscriptionPage struct {
+	Title   string
+	Message string
+	Token   string
+}
+
+// SubscriptionHandler serves digest subscriptions: the API that subscribes
+// an address and the confirmation and unsubscribe links sent by email
+type SubscriptionHandler struct {
+	db     *sql.DB
+	digest *digestMailer
+}
+
+func NewSubscriptionHandler(db *sql.DB, digest *digestMailer) *SubscriptionHandler {
+	return &SubscriptionHandler{db: db, digest: digest}
+}
+
+func (h *SubscriptionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/subscriptions":
+		h.Subscribe(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/subscriptions/confirm":
+		h.Confirm(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/subscriptions/unsubscribe":
+		h.ConfirmUnsubscribe(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/subscriptions/unsubscribe":
+		h.Unsubscribe(w, r)
+	default:
+		NotFoundHandler(w, r)
+	}
+}
+
+func (h *SubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	var request subscriptionRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	sub := Subscriber{Email: request.Email, Country: request.Country, Frequency: request.Frequency}
+	if err := validateSubscriber(&sub); err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	if err := saveSubscriber(h.db, &sub); err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+	if sub.Confirmed {
+		// Already subscribed, there is nothing to confirm
+		writeJSON(w, r, http.StatusAccepted, sub)
+		return
+	}
+	if err := h.digest.SendConfirmation(sub); err != nil {
+		log.Printf("Confirmation email to %s failed: %v", sub.Email, err)
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeJSON(w, r, http.StatusAccepted, sub)
+}
+
+func (h *SubscriptionHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	err := confirmSubscriber(h.db, r.URL.Query().Get("token"))
+	if err == SubscriberNotFoundErr {
+		NotFoundHandler(w, r)
+		return
+	}
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writePage(w, r, "subscription", subscriptionPage{
+		Title:   "Subscription confirmed",
+		Message: "You will now receive the nameday digest by email.",
+	})
+}
+
+// ConfirmUnsubscribe asks to confirm the unsubscribe link of an email. It
+// does not unsubscribe, since link scanners follow every link in a message.
+func (h *SubscriptionHandler) ConfirmUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	_, err := subscriberByToken(h.db, token)
+	if err == SubscriberNotFoundErr {
+		NotFoundHandler(w, r)
+		return
+	}
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writePage(w, r, "subscription", subscriptionPage{
+		Title:   "Unsubscribe",
+		Message: "Stop receiving the nameday digest by email?",
+		Token:   token,
+	})
+}
+
+// Unsubscribe removes a subscriber, either from the form of
+// ConfirmUnsubscribe or as a one-click unsubscribe from mail clients
+// (RFC 8058), which POST to the link with the token in the query.
+func (h *SubscriptionHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	err := unsubscribe(h.db, r.FormValue("token"))
+	if err == SubscriberNotFoundErr {
+		NotFoundHandler(w, r)
+		return
+	}
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writePage(w, r, "subscription", subscriptionPage{
+		Title:   "Unsubscribed",
+		Message: "You will no longer receive the nameday digest.",
+	})
+}
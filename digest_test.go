@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func createTestDigest(t *testing.T) (*digestMailer, *testSMTPServer) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "06-24", "Jānis")
+	insertTestNames(t, db, "06-26", "Ausma")
+
+	server := startTestSMTPServer(t, nil)
+	digest, err := NewDigestMailer(db, NewMailer(server.config()), "https://namedays.example.com/", DefaultDigestTime)
+	if err != nil {
+		t.Fatalf("NewDigestMailer returned an error: %v", err)
+	}
+	return digest, server
+}
+
+// subscribeTestAddress subscribes an address through the API and confirms
+// it, returning its token
+func subscribeTestAddress(t *testing.T, handler http.Handler, db *sql.DB, body string) string {
+	rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/subscriptions", []byte(body))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusAccepted)
+
+	var token string
+	if err := db.QueryRow("SELECT token FROM subscribers ORDER BY id DESC LIMIT 1").Scan(&token); err != nil {
+		t.Fatal("Failed to read token:", err)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/subscriptions/confirm?token="+token, nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	return token
+}
+
+func TestSubscriptionFlow(t *testing.T) {
+	digest, server := createTestDigest(t)
+	handler := NewSubscriptionHandler(digest.db, digest)
+
+	rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/subscriptions", []byte(`{"email":"anna@example.com"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusAccepted)
+	if body := rr.Body.String(); strings.Contains(body, "token") || !strings.Contains(body, `"confirmed":false`) {
+		t.Errorf("Expected an unconfirmed subscriber without its token, got %s", body)
+	}
+
+	// Nothing is sent before the address is confirmed
+	digest.RunOnce(context.Background(), midsummer)
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("Expected only the confirmation email, got %d messages", len(messages))
+	}
+	sub, err := subscriberByToken(digest.db, tokenOf(t, digest.db, "anna@example.com"))
+	if err != nil {
+		t.Fatalf("subscriberByToken returned an error: %v", err)
+	}
+	_, text, _ := readTestEmail(t, messages[0].Data)
+	if !strings.Contains(text, "https://namedays.example.com/subscriptions/confirm?token="+sub.Token) {
+		t.Errorf("Expected a confirmation link, got %s", text)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/subscriptions/confirm?token="+sub.Token, nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+
+	digest.RunOnce(context.Background(), midsummer)
+	digest.RunOnce(context.Background(), midsummer)
+	messages = server.received()
+	if len(messages) != 2 {
+		t.Fatalf("Expected a single digest, got %d messages", len(messages))
+	}
+	header, text, html := readTestEmail(t, messages[1].Data)
+	unsubscribeLink := "https://namedays.example.com/subscriptions/unsubscribe?token=" + sub.Token
+	if header.Get("List-Unsubscribe") != "<"+unsubscribeLink+">" {
+		t.Errorf("Unexpected List-Unsubscribe header: %s", header.Get("List-Unsubscribe"))
+	}
+	if !strings.Contains(text, "Monday, 24 June: Jānis") || !strings.Contains(text, unsubscribeLink) {
+		t.Errorf("Unexpected plain text digest: %s", text)
+	}
+	if !strings.Contains(html, "<li>Jānis</li>") {
+		t.Errorf("Unexpected HTML digest: %s", html)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodPost, "/subscriptions/unsubscribe?token="+sub.Token, []byte("List-Unsubscribe=One-Click"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+
+	digest.RunOnce(context.Background(), midsummer.AddDate(0, 0, 1))
+	if len(server.received()) != 2 {
+		t.Error("Expected no digest after unsubscribing")
+	}
+}
+
+func TestUnsubscribeLinkAsksToConfirm(t *testing.T) {
+	digest, _ := createTestDigest(t)
+	handler := NewSubscriptionHandler(digest.db, digest)
+	token := subscribeTestAddress(t, handler, digest.db, `{"email":"anna@example.com"}`)
+
+	// Following the link, as link scanners do, must not unsubscribe
+	rr, req := setupTestRequest(t, http.MethodGet, "/subscriptions/unsubscribe?token="+token, nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	body := rr.Body.String()
+	if !strings.Contains(body, `<form method="post" action="/subscriptions/unsubscribe">`) || !strings.Contains(body, `value="`+token+`"`) {
+		t.Errorf("Expected a form posting the token, got %s", body)
+	}
+	if _, err := subscriberByToken(digest.db, token); err != nil {
+		t.Fatalf("Expected the subscriber to be kept, got %v", err)
+	}
+
+	// Submitting the form does
+	rr, req = setupTestRequest(t, http.MethodPost, "/subscriptions/unsubscribe", []byte("token="+token))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	if _, err := subscriberByToken(digest.db, token); err != SubscriberNotFoundErr {
+		t.Errorf("Expected the subscriber to be removed, got %v", err)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/subscriptions/unsubscribe?token="+token, nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusNotFound)
+}
+
+func tokenOf(t *testing.T, db *sql.DB, email string) string {
+	var token string
+	if err := db.QueryRow("SELECT token FROM subscribers WHERE email = ?", email).Scan(&token); err != nil {
+		t.Fatal("Failed to read token:", err)
+	}
+	return token
+}
+
+func TestWeeklyDigest(t *testing.T) {
+	digest, server := createTestDigest(t)
+	handler := NewSubscriptionHandler(digest.db, digest)
+	subscribeTestAddress(t, handler, digest.db, `{"email":"anna@example.com","frequency":"weekly"}`)
+
+	// Weekly digests only go out on Mondays
+	digest.RunOnce(context.Background(), midsummer.AddDate(0, 0, 1))
+	if len(server.received()) != 1 {
+		t.Fatalf("Expected no digest on a Tuesday, got %d messages", len(server.received()))
+	}
+
+	digest.RunOnce(context.Background(), midsummer.AddDate(0, 0, 7))
+	messages := server.received()
+	if len(messages) != 2 {
+		t.Fatalf("Expected a digest on Monday, got %d messages", len(messages))
+	}
+	header, text, _ := readTestEmail(t, messages[1].Data)
+	if subject := header.Get("Subject"); !strings.Contains(subject, "week") {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if strings.Count(text, "\n") < 7 || !strings.Contains(text, "Monday, 1 July: no namedays") {
+		t.Errorf("Expected seven days in the digest, got %s", text)
+	}
+}
+
+func TestResubscribeUnconfirmed(t *testing.T) {
+	addTestCountries(t)
+	digest, server := createTestDigest(t)
+	handler := NewSubscriptionHandler(digest.db, digest)
+
+	for _, body := range []string{`{"email":"anna@example.com"}`, `{"email":"anna@example.com","country":"lt"}`} {
+		rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/subscriptions", []byte(body))
+		handler.ServeHTTP(rr, req)
+		checkResponseStatus(t, rr, http.StatusAccepted)
+	}
+
+	sub, err := subscriberByToken(digest.db, tokenOf(t, digest.db, "anna@example.com"))
+	if err != nil {
+		t.Fatalf("subscriberByToken returned an error: %v", err)
+	}
+	if sub.Confirmed || sub.Country != "lt" {
+		t.Errorf("Expected the unconfirmed subscription to be replaced, got %+v", sub)
+	}
+	digest.RunOnce(context.Background(), midsummer)
+	if len(server.received()) != 2 {
+		t.Errorf("Expected two confirmation emails and no digest, got %d messages", len(server.received()))
+	}
+}
+
+func TestResubscribeConfirmed(t *testing.T) {
+	addTestCountries(t)
+	digest, server := createTestDigest(t)
+	handler := NewSubscriptionHandler(digest.db, digest)
+	token := subscribeTestAddress(t, handler, digest.db, `{"email":"anna@example.com"}`)
+
+	rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/subscriptions", []byte(`{"email":"anna@example.com","country":"lt"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusAccepted)
+	if body := rr.Body.String(); !strings.Contains(body, `"confirmed":true`) || !strings.Contains(body, `"country":"lv"`) {
+		t.Errorf("Expected the confirmed subscriber, got %s", body)
+	}
+
+	sub, err := subscriberByToken(digest.db, token)
+	if err != nil {
+		t.Fatalf("Expected the token to be kept, got %v", err)
+	}
+	if !sub.Confirmed || sub.Country != "lv" {
+		t.Errorf("Expected the subscription to be unchanged, got %+v", sub)
+	}
+	if len(server.received()) != 1 {
+		t.Errorf("Expected no second confirmation email, got %d messages", len(server.received()))
+	}
+}
+
+func TestSubscriptionHandlerInvalidRequests(t *testing.T) {
+	digest, server := createTestDigest(t)
+	handler := NewSubscriptionHandler(digest.db, digest)
+
+	invalid := []string{
+		`{"email":"not an address"}`,
+		`{"email":"Anna <anna@example.com>"}`,
+		`{"email":"anna@example.com","frequency":"hourly"}`,
+		`{"email":"anna@example.com","country":"xx"}`,
+		`{"email":"anna@example.com","confirmed":true}`,
+	}
+	for _, body := range invalid {
+		rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/subscriptions", []byte(body))
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+
+	for _, path := range []string{"/subscriptions/confirm?token=nope", "/subscriptions/unsubscribe", "/subscriptions/other"} {
+		rr, req := setupTestRequest(t, http.MethodGet, path, nil)
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s, got %d", path, rr.Code)
+		}
+	}
+
+	if len(server.received()) != 0 {
+		t.Errorf("Expected no emails, got %d", len(server.received()))
+	}
+}
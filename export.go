@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/zrks/namedays/pkg/importer"
+)
+
+type exportHandler struct {
+	db *sql.DB
+}
+
+func NewExportHandler(db *sql.DB) *exportHandler {
+	return &exportHandler{db: db}
+}
+
+// ServeHTTP exports the dataset as CSV. Every country is exported unless
+// the country query parameter picks one.
+func (h *exportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	country := strings.ToLower(r.URL.Query().Get("country"))
+	if _, ok := Countries[country]; country != "" && !ok {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	records, err := importer.ExportRecords(h.db, country)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = importer.WriteCSV(&buf, records, ','); err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="namedays.csv"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
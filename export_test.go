@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/zrks/namedays/pkg/namedays"
+)
+
+func TestExportHandler(t *testing.T) {
+	addTestCountries(t)
+	_, db := createTestDb(t)
+	store := namedays.NewSQLStore(db, defaultCountry)
+	store.Add("janis", namedays.Nameday{Name: "Jānis", Date: "06-24", Country: "lv"})
+	store.Add("jonas", namedays.Nameday{Name: "Jonas", Date: "06-24", Country: "lt"})
+	handler := NewExportHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/export.csv", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	if ct := rr.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("Unexpected content type: %s", ct)
+	}
+	expected := "date,name,country,gender\n06-24,Jonas,lt,\n06-24,Jānis,lv,\n"
+	if body := rr.Body.String(); body != expected {
+		t.Errorf("Unexpected body:\n%s", body)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/export.csv?country=lv", nil)
+	handler.ServeHTTP(rr, req)
+	if body := rr.Body.String(); body != "date,name,country,gender\n06-24,Jānis,lv,\n" {
+		t.Errorf("Unexpected body for lv:\n%s", body)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/export.csv?country=xx", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusBadRequest)
+}
@@ -1,18 +1,18 @@
-module k8s
+module github.com/zrks/namedays
 
 go 1.17
 
 require (
 	github.com/gosimple/slug v1.15.0
+	github.com/gosimple/unidecode v1.0.1
+	github.com/mattn/go-sqlite3 v1.14.24
 	github.com/stretchr/testify v1.8.1
 	github.com/xNok/go-rest-demo v0.0.0-20231003210758-5a627212098b
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/gosimple/unidecode v1.0.1 // indirect
 	github.com/kr/pretty v0.3.0 // indirect
-	github.com/mattn/go-sqlite3 v1.14.24 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rogpeppe/go-internal v1.8.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultSMTPPort    = 587
+	DefaultSMTPTimeout = 30 * time.Second
+)
+
+var (
+	StartTLSUnsupportedErr = errors.New("SMTP server does not support STARTTLS")
+)
+
+// SMTPConfig describes the server used to send email. With StartTLS set
+// the connection must be upgraded before authenticating.
+type SMTPConfig struct {
+	Host      string
+	Port      int
+	Username  string
+	Password  string
+	From      string
+	StartTLS  bool
+	TLSConfig *tls.Config
+	Timeout   time.Duration
+}
+
+// Email is a message with plain text and HTML alternatives
+type Email struct {
+	To      string
+	Subject string
+	Text    []byte
+	HTML    []byte
+	Headers map[string]string
+}
+
+// Mailer sends email through an SMTP server
+type Mailer struct {
+	config SMTPConfig
+}
+
+func NewMailer(config SMTPConfig) *Mailer {
+	if config.Port == 0 {
+		config.Port = DefaultSMTPPort
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultSMTPTimeout
+	}
+	return &Mailer{config: config}
+}
+
+// Send delivers an email to its single recipient
+func (m *Mailer) Send(email Email) error {
+	msg, err := m.buildMessage(email)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	conn, err := net.DialTimeout("tcp", addr, m.config.Timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(m.config.Timeout))
+
+	c, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to greet %s: %w", addr, err)
+	}
+	defer c.Close()
+
+	if m.config.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return StartTLSUnsupportedErr
+		}
+		tlsConfig := m.config.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: m.config.Host}
+		}
+		if err = c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if m.config.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err = c.Mail(m.config.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	if err = c.Rcpt(email.To); err != nil {
+		return fmt.Errorf("failed to set recipient: %w", err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err = w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return c.Quit()
+}
+
+// buildMessage encodes an email as a multipart/alternative MIME message
+// with quoted-printable UTF-8 parts
+func (m *Mailer) buildMessage(email Email) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, alternative := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=UTF-8", email.Text},
+		{"text/html; charset=UTF-8", email.HTML},
+	} {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alternative.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(part)
+		if _, err = qp.Write(alternative.content); err != nil {
+			return nil, err
+		}
+		if err = qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	headers := [][2]string{
+		{"From", m.config.From},
+		{"To", email.To},
+		{"Subject", mime.QEncoding.Encode("UTF-8", email.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", "<" + hex.EncodeToString(id) + "@namedays>"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h[0], h[1])
+	}
+	names := make([]string, 0, len(email.Headers))
+	for name := range email.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&msg, "%s: %s\r\n", name, email.Headers[name])
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSMTPMessage is an email received by testSMTPServer
+type testSMTPMessage struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// testSMTPServer is an in-process SMTP stand-in. Setting tlsConfig offers
+// STARTTLS and setting username requires AUTH PLAIN before sending.
+type testSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	username  string
+	password  string
+
+	mu       sync.Mutex
+	messages []testSMTPMessage
+}
+
+func startTestSMTPServer(t *testing.T, configure func(s *testSMTPServer)) *testSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen:", err)
+	}
+	s := &testSMTPServer{listener: listener}
+	if configure != nil {
+		configure(s)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// config returns an SMTP configuration pointing at the server
+func (s *testSMTPServer) config() SMTPConfig {
+	port := s.listener.Addr().(*net.TCPAddr).Port
+	return SMTPConfig{Host: "127.0.0.1", Port: port, From: "namedays@example.com"}
+}
+
+// received returns the messages delivered so far
+func (s *testSMTPServer) received() []testSMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]testSMTPMessage(nil), s.messages...)
+}
+
+func (s *testSMTPServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP test")
+
+	secure, authenticated := false, false
+	var current testSMTPMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg := line, ""
+		if i := strings.Index(line, " "); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			extensions := []string{"localhost", "8BITMIME", "AUTH PLAIN"}
+			if s.tlsConfig != nil && !secure {
+				extensions = append(extensions, "STARTTLS")
+			}
+			for i, ext := range extensions {
+				sep := "-"
+				if i == len(extensions)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, ext)
+			}
+		case "STARTTLS":
+			tp.PrintfLine("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, tp, secure = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			if string(credentials) == "\x00"+s.username+"\x00"+s.password {
+				authenticated = true
+				tp.PrintfLine("235 Authentication successful")
+			} else {
+				tp.PrintfLine("535 Authentication failed")
+			}
+		case "MAIL":
+			if s.username != "" && !authenticated {
+				tp.PrintfLine("530 Authentication required")
+				continue
+			}
+			current = testSMTPMessage{From: smtpPath(arg)}
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			current.To = append(current.To, smtpPath(arg))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 Go ahead")
+			current.Data, err = tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, current)
+			s.mu.Unlock()
+			tp.PrintfLine("250 OK")
+		case "RSET", "NOOP":
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// smtpPath extracts the address from a MAIL or RCPT argument such as
+// "FROM:<a@example.com> BODY=8BITMIME"
+func smtpPath(arg string) string {
+	start, end := strings.Index(arg, "<"), strings.Index(arg, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return arg[start+1 : end]
+}
+
+// testTLSConfigs returns matching server and client configurations with a
+// self-signed certificate for 127.0.0.1
+func testTLSConfigs(t *testing.T) (*tls.Config, *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("Failed to generate key:", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal("Failed to create certificate:", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal("Failed to parse certificate:", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	server := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client := &tls.Config{ServerName: "127.0.0.1", RootCAs: roots}
+	return server, client
+}
+
+// readTestEmail parses a received message into its headers and the
+// decoded plain text and HTML parts
+func readTestEmail(t *testing.T, data []byte) (mail.Header, string, string) {
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected a multipart/alternative message, got %q", msg.Header.Get("Content-Type"))
+	}
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		content, _ := io.ReadAll(part)
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[partType] = string(content)
+	}
+	return msg.Header, parts["text/plain"], parts["text/html"]
+}
+
+func TestMailerSend(t *testing.T) {
+	server := startTestSMTPServer(t, nil)
+	mailer := NewMailer(server.config())
+
+	err := mailer.Send(Email{
+		To:      "anna@example.com",
+		Subject: "Vārda dienas",
+		Text:    []byte("Jānis"),
+		HTML:    []byte("<b>Jānis</b>"),
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+	})
+	if err != nil {
+		t.Fatalf("Send returned an error: %v", err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("Expected one message, got %d", len(messages))
+	}
+	if messages[0].From != "namedays@example.com" || len(messages[0].To) != 1 || messages[0].To[0] != "anna@example.com" {
+		t.Errorf("Unexpected envelope: %v -> %v", messages[0].From, messages[0].To)
+	}
+
+	header, text, html := readTestEmail(t, messages[0].Data)
+	subject, _ := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	if subject != "Vārda dienas" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if header.Get("List-Unsubscribe") != "<https://example.com/u>" {
+		t.Errorf("Expected the extra header, got %v", header)
+	}
+	if text != "Jānis" || html != "<b>Jānis</b>" {
+		t.Errorf("Unexpected parts: %q, %q", text, html)
+	}
+}
+
+func TestMailerStartTLSAndAuth(t *testing.T) {
+	serverTLS, clientTLS := testTLSConfigs(t)
+	server := startTestSMTPServer(t, func(s *testSMTPServer) {
+		s.tlsConfig = serverTLS
+		s.username, s.password = "namedays", "s3cret"
+	})
+
+	config := server.config()
+	config.StartTLS = true
+	config.TLSConfig = clientTLS
+	config.Username, config.Password = "namedays", "s3cret"
+	if err := NewMailer(config).Send(Email{To: "anna@example.com", Subject: "Test"}); err != nil {
+		t.Fatalf("Send returned an error: %v", err)
+	}
+	if len(server.received()) != 1 {
+		t.Errorf("Expected one message, got %d", len(server.received()))
+	}
+
+	config.Password = "wrong"
+	if err := NewMailer(config).Send(Email{To: "anna@example.com", Subject: "Test"}); err == nil {
+		t.Error("Expected an error for a wrong password")
+	}
+}
+
+func TestMailerRequiresStartTLS(t *testing.T) {
+	server := startTestSMTPServer(t, nil)
+
+	config := server.config()
+	config.StartTLS = true
+	if err := NewMailer(config).Send(Email{To: "anna@example.com"}); !errors.Is(err, StartTLSUnsupportedErr) {
+		t.Errorf("Expected StartTLSUnsupportedErr, got %v", err)
+	}
+	if len(server.received()) != 0 {
+		t.Error("Expected nothing to be sent without TLS")
+	}
+}
@@ -1,99 +1,202 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"mime"
 	"net/http"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gosimple/slug"
 	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/zrks/namedays/pkg/importer"
+	"github.com/zrks/namedays/pkg/migrations"
+	"github.com/zrks/namedays/pkg/namedays"
 )
 
 var (
 	NamedayRe       = regexp.MustCompile(`^/nameday/*$`)
-	NamedayReWithID = regexp.MustCompile(`^/nameday/([a-z0-9]+(?:-[a-z0-9]+)+)$`)
+	NamedayReWithID = regexp.MustCompile(`^/nameday/([^/]+)$`)
 )
 
-// insertNamedaysFromJSON inserts namedays from JSON file into the database
-func insertNamedaysFromJSON(db *sql.DB) error {
-	jsonData, err := os.ReadFile("db-ops/namedays.json")
+// insertNamedaysFromJSON inserts a country's namedays from a JSON dataset into the database
+func insertNamedaysFromJSON(db *sql.DB, ds Dataset) error {
+	jsonData, err := fs.ReadFile(ds.FS, ds.Name)
 	if err != nil {
-		return fmt.Errorf("failed to read namedays.json: %w", err)
-	}
-
-	var namedays map[string][]string
-	if err := json.Unmarshal(jsonData, &namedays); err != nil {
-		return fmt.Errorf("failed to parse namedays.json: %w", err)
+		return fmt.Errorf("failed to read %s: %w", ds.Name, err)
 	}
 
-	tx, err := db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+	var namedayData importer.Namedays
+	if err := json.Unmarshal(jsonData, &namedayData); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", ds.Name, err)
 	}
 
-	stmt, err := tx.Prepare("INSERT INTO namedays (date, name) VALUES (?, ?)")
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to prepare statement: %w", err)
+	if _, err = importer.Import(db, namedayData, importer.Options{Country: ds.Country}); err != nil {
+		return fmt.Errorf("failed to import %s: %w", ds.Name, err)
 	}
-	defer stmt.Close()
-
-	for date, names := range namedays {
-		for _, name := range names {
-			if _, err = stmt.Exec(date, name); err != nil {
-				tx.Rollback()
-				return fmt.Errorf("failed to insert nameday: %w", err)
-			}
-		}
-	}
-
-	return tx.Commit()
+	return nil
 }
 
-// InitDB ensures the database exists and has the proper schema
-func InitDB(dbPath string) error {
+// InitDB ensures the database exists, has the proper schema and holds
+// every country in datasets
+func InitDB(dbPath string, datasets []Dataset) error {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer db.Close()
 
-	query := `CREATE TABLE IF NOT EXISTS namedays (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT NOT NULL, name TEXT NOT NULL);`
-	if _, err = db.Exec(query); err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
+	if err = migrations.Up(db); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
-	var count int
-	if err = db.QueryRow("SELECT COUNT(*) FROM namedays").Scan(&count); err != nil {
-		return fmt.Errorf("failed to check table count: %w", err)
-	}
+	// Load each bundled country the first time it is seen. Overrides are
+	// applied on every start, importing is idempotent so only changes to
+	// the dataset are written.
+	for _, ds := range datasets {
+		var count int
+		if err = db.QueryRow("SELECT COUNT(*) FROM namedays WHERE country = ?", ds.Country).Scan(&count); err != nil {
+			return fmt.Errorf("failed to check table count: %w", err)
+		}
 
-	if count == 0 {
-		fmt.Println("Reading namedays from JSON file...")
-		if err = insertNamedaysFromJSON(db); err != nil {
-			return err
+		if count == 0 || ds.Override {
+			fmt.Printf("Reading %s namedays from %s...\n", ds.Country, ds.Name)
+			if err = insertNamedaysFromJSON(db, ds); err != nil {
+				return err
+			}
+			fmt.Println("Namedays data inserted successfully")
 		}
-		fmt.Println("Namedays data inserted successfully")
 	}
 
 	return nil
 }
 
 func main() {
+	dataPath := flag.String("data", os.Getenv("NAMEDAYS_DATA"), "JSON dataset file or directory of <country>.json files overriding the bundled data")
+	syncURL := flag.String("sync-url", os.Getenv("NAMEDAYS_SYNC_URL"), "URL of a JSON dataset to re-import periodically")
+	syncSHA256 := flag.String("sync-sha256", os.Getenv("NAMEDAYS_SYNC_SHA256"), "expected SHA-256 checksum of the dataset at -sync-url")
+	syncInterval := flag.Duration("sync-interval", 24*time.Hour, "how often to re-import the dataset from -sync-url")
+	syncCountry := flag.String("sync-country", "", "country of the dataset at -sync-url (defaults to the default country)")
+	syncPrune := flag.Bool("sync-prune", false, "delete names that are no longer in the dataset at -sync-url (names added through the API are kept)")
+	smtpHost := flag.String("smtp-host", os.Getenv("NAMEDAYS_SMTP_HOST"), "SMTP server for the email digest (the digest is disabled when empty)")
+	smtpPort := flag.String("smtp-port", envOrDefault("NAMEDAYS_SMTP_PORT", strconv.Itoa(DefaultSMTPPort)), "SMTP server port")
+	smtpUsername := flag.String("smtp-username", os.Getenv("NAMEDAYS_SMTP_USERNAME"), "SMTP username, authentication is skipped when empty")
+	smtpPassword := flag.String("smtp-password", os.Getenv("NAMEDAYS_SMTP_PASSWORD"), "SMTP password")
+	smtpFrom := flag.String("smtp-from", envOrDefault("NAMEDAYS_SMTP_FROM", "namedays@localhost"), "sender address of the email digest")
+	smtpStartTLS := flag.Bool("smtp-starttls", envOrDefault("NAMEDAYS_SMTP_STARTTLS", "true") != "false", "require STARTTLS before authenticating")
+	digestTime := flag.String("digest-time", envOrDefault("NAMEDAYS_DIGEST_TIME", DefaultDigestTime), "local time of day (HH:MM) at which the email digest is sent")
+	baseURL := flag.String("base-url", envOrDefault("NAMEDAYS_BASE_URL", DefaultBaseURL), "public URL of the server, used in email links")
+	webhookTime := flag.String("webhook-time", envOrDefault("NAMEDAYS_WEBHOOK_TIME", DefaultWebhookTime), "local time of day (HH:MM) at which webhooks are delivered")
+	webhookAllowPrivate := flag.Bool("webhook-allow-private", os.Getenv("NAMEDAYS_WEBHOOK_ALLOW_PRIVATE") == "true", "allow webhooks to loopback, private and link-local addresses")
+	flag.Parse()
+
+	if tz := os.Getenv("NAMEDAYS_TIMEZONE"); tz != "" {
+		if err := SetDefaultTimezone(tz); err != nil {
+			fmt.Printf("Error configuring timezone: %v\n", err)
+			return
+		}
+	}
+
+	if country := os.Getenv("NAMEDAYS_COUNTRY"); country != "" {
+		if err := SetDefaultCountry(country); err != nil {
+			fmt.Printf("Error configuring country: %v\n", err)
+			return
+		}
+	}
+
+	datasets, err := LoadDatasets(*dataPath)
+	if err != nil {
+		fmt.Printf("Error loading datasets: %v\n", err)
+		return
+	}
+
 	// Initialize database
 	dbPath := "./namedays.db"
-	if err := InitDB(dbPath); err != nil {
+	if err := InitDB(dbPath, datasets); err != nil {
 		fmt.Printf("Error initializing database: %v\n", err)
 		return
 	}
 
-	store := NewMemStore()
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		fmt.Printf("Error opening database: %v\n", err)
+		return
+	}
+	defer db.Close()
+
+	if err = LoadCountries(db); err != nil {
+		fmt.Printf("Error reading loaded countries: %v\n", err)
+		return
+	}
+	if !countryLoaded(defaultCountry) {
+		fmt.Printf("Warning: no namedays are loaded for the default country %s\n", defaultCountry)
+	}
+
+	if *syncURL != "" {
+		country := *syncCountry
+		if country == "" {
+			country = defaultCountry
+		}
+		if _, ok := Countries[country]; !ok {
+			fmt.Printf("Error configuring sync: unsupported country %q\n", country)
+			return
+		}
+		if *syncInterval <= 0 {
+			fmt.Printf("Error configuring sync: -sync-interval must be positive, got %s\n", *syncInterval)
+			return
+		}
+		if sum, err := hex.DecodeString(*syncSHA256); err != nil || (*syncSHA256 != "" && len(sum) != sha256.Size) {
+			fmt.Printf("Error configuring sync: -sync-sha256 must be a hex SHA-256 checksum, got %q\n", *syncSHA256)
+			return
+		}
+		go NewDatasetSync(db, *syncURL, *syncSHA256, country, *syncPrune, *syncInterval).Run(context.Background())
+	}
+
+	scheduler, err := NewWebhookScheduler(db, *webhookTime, *webhookAllowPrivate)
+	if err != nil {
+		fmt.Printf("Error configuring webhooks: %v\n", err)
+		return
+	}
+	go scheduler.Run(context.Background())
+
+	var digest *digestMailer
+	if *smtpHost != "" {
+		port, err := strconv.Atoi(*smtpPort)
+		if err != nil {
+			fmt.Printf("Error configuring SMTP: invalid port %q\n", *smtpPort)
+			return
+		}
+		mailer := NewMailer(SMTPConfig{
+			Host:     *smtpHost,
+			Port:     port,
+			Username: *smtpUsername,
+			Password: *smtpPassword,
+			From:     *smtpFrom,
+			StartTLS: *smtpStartTLS,
+		})
+		if digest, err = NewDigestMailer(db, mailer, *baseURL, *digestTime); err != nil {
+			fmt.Printf("Error configuring the email digest: %v\n", err)
+			return
+		}
+		go digest.Run(context.Background())
+	}
+
+	store := namedays.NewSQLStore(db, defaultCountry)
 	namedayHandler := NewNamedayHandler(store)
 	homeHandler := NewHomeHandler(dbPath)
 	mux := http.NewServeMux()
@@ -101,23 +204,71 @@ func main() {
 	mux.Handle("/", homeHandler)
 	mux.Handle("/nameday", namedayHandler)
 	mux.Handle("/nameday/", namedayHandler)
+	mux.Handle("/api/v1/date/", NewDateHandler(db))
+	mux.Handle("/api/v1/names/", NewNameHandler(db))
+	mux.Handle("/api/v1/search", NewSearchHandler(db))
+	mux.Handle("/calendar.ics", NewCalendarHandler(db))
+	mux.Handle("/export.csv", NewExportHandler(db))
+	mux.Handle("/api/v1/month/", NewMonthHandler(db))
+	mux.Handle("/month/", NewMonthPageHandler(db))
+	mux.Handle("/api/v1/range", NewRangeHandler(db))
+	mux.Handle("/api/v1/upcoming", NewUpcomingHandler(db))
+	mux.Handle("/api/v1/contacts", NewContactsHandler(db))
+	mux.Handle("/api/v1/contacts/upcoming", NewContactsUpcomingHandler(db))
+	mux.Handle("/api/v1/today/message", NewMessagePreviewHandler(db))
+	webhookHandler := NewWebhookHandler(db, scheduler)
+	mux.Handle("/api/v1/webhooks", webhookHandler)
+	mux.Handle("/api/v1/webhooks/", webhookHandler)
+	if digest != nil {
+		subscriptionHandler := NewSubscriptionHandler(db, digest)
+		mux.Handle("/api/v1/subscriptions", subscriptionHandler)
+		mux.Handle("/subscriptions/", subscriptionHandler)
+	}
 
 	fmt.Println("Server starting on :8080...")
 	http.ListenAndServe(":8080", mux)
 }
 
+// envOrDefault returns the value of an environment variable, or def when
+// it is unset
+func envOrDefault(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+type homePage struct {
+	Country string
+	Date    string
+	Names   []string
+}
+
 type homeHandler struct {
 	dbPath string
+	clock  Clock
 }
 
 func NewHomeHandler(dbPath string) *homeHandler {
 	if dbPath == "" {
 		dbPath = "./namedays.db"
 	}
-	return &homeHandler{dbPath: dbPath}
+	return &homeHandler{dbPath: dbPath, clock: systemClock{}}
 }
 
 func (h *homeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now, err := requestNow(h.clock, r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
 	// Open the database connection
 	db, err := sql.Open("sqlite3", h.dbPath)
 	if err != nil {
@@ -127,273 +278,295 @@ func (h *homeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	// Get today's namedays
-	names, err := getNameday(db)
+	names, err := getNamedaysForDate(db, country, now.Format("01-02"))
 	if err != nil {
 		InternalServerErrorHandler(w, r)
 		return
 	}
 
-	// Create HTML response
-	var sb strings.Builder
-	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
-	sb.WriteString("  <meta charset=\"UTF-8\">\n  <meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\">\n")
-	sb.WriteString("  <title>Today's Namedays</title>\n</head>\n<body>\n")
-	sb.WriteString(fmt.Sprintf("  <h1>Namedays for %s</h1>\n", time.Now().Format("January 2")))
-
-	if len(names) == 0 {
-		sb.WriteString("  <p>No namedays found for today</p>\n")
-	} else {
-		sb.WriteString("  <ul>\n")
-		for _, name := range names {
-			sb.WriteString(fmt.Sprintf("    <li>%s</li>\n", name))
-		}
-		sb.WriteString("  </ul>\n")
-	}
-
-	sb.WriteString("</body>\n</html>")
-	w.Write([]byte(sb.String()))
-}
-
-func getNameday(db *sql.DB) ([]string, error) {
-	// Get today's date in the format "MM-DD"
-	today := time.Now().Format("01-02")
-
-	// Query the database for names on today's date
-	rows, err := db.Query("SELECT name FROM namedays WHERE date = ?", today)
-	if err != nil {
-		return nil, fmt.Errorf("error querying database: %w", err)
-	}
-	defer rows.Close()
-
-	// Collect all names for today
-	var names []string
-	for rows.Next() {
-		var name string
-		if err := rows.Scan(&name); err != nil {
-			return nil, fmt.Errorf("error scanning row: %w", err)
-		}
-		names = append(names, name)
-	}
-
-	// Check for any errors during iteration
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
-	return names, nil
+	writePage(w, r, "home", homePage{
+		Country: strings.ToUpper(country),
+		Date:    now.Format("January 2"),
+		Names:   names,
+	})
 }
 
+// ReadJSONFromURL downloads a JSON dataset with the default timeout and size limit
 func ReadJSONFromURL(url string) (map[string][]string, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching URL: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-OK HTTP status: %d", resp.StatusCode)
-	}
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
-	}
-
-	var result map[string][]string
-	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, fmt.Errorf("error parsing JSON: %w", err)
-	}
-
-	return result, nil
+	return importer.NewFetcher().Fetch(context.Background(), url)
 }
 
 func FilterNamedaysByMonth(namedays map[string][]string, month string) []string {
-	var result []string
-	for date, names := range namedays {
+	var dates []string
+	for date := range namedays {
 		if strings.HasPrefix(date, month) {
-			result = append(result, fmt.Sprintf("%s: %s", date, strings.Join(names, ", ")))
+			dates = append(dates, date)
 		}
 	}
+	sort.Strings(dates)
+
+	var result []string
+	for _, date := range dates {
+		result = append(result, fmt.Sprintf("%s: %s", date, strings.Join(namedays[date], ", ")))
+	}
 	return result
 }
 
 func RenderHTMLList(items []string) string {
-	var sb strings.Builder
-	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
-	sb.WriteString("  <meta charset=\"UTF-8\">\n  <meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\">\n")
-	sb.WriteString("  <title>Namedays</title>\n</head>\n<body>\n  <ul>\n")
-
-	for _, item := range items {
-		sb.WriteString(fmt.Sprintf("    <li>%s</li>\n", item))
+	html, err := renderPage("list", items)
+	if err != nil {
+		return ""
 	}
-
-	sb.WriteString("  </ul>\n</body>\n</html>")
-	return sb.String()
-}
-
-func GetCurrentMonth() string {
-	return time.Now().Format("01")
+	return string(html)
 }
 
-type namedayStore interface {
-	Add(name string, nameday Nameday) error
-	Get(name string) (Nameday, error)
-	List() (map[string]Nameday, error)
-	Update(name string, nameday Nameday) error
-	Remove(name string) error
+type NamedayHandler struct {
+	store namedays.Store
 }
 
-type Nameday struct {
-	Name string `json:"name"`
-	Date string `json:"date"`
+func NewNamedayHandler(s namedays.Store) *NamedayHandler {
+	return &NamedayHandler{store: s}
 }
 
-type NamedayHandler struct {
-	store namedayStore
+func (h *NamedayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case NamedayRe.MatchString(r.URL.Path):
+		switch r.Method {
+		case http.MethodGet:
+			h.ListNamedays(w, r)
+		case http.MethodPost:
+			h.CreateNameday(w, r)
+		default:
+			methodNotAllowed(w, r, "GET, POST")
+		}
+	case namedayID(r.URL.Path) != "":
+		switch r.Method {
+		case http.MethodGet:
+			h.GetNameday(w, r)
+		case http.MethodPut:
+			h.UpdateNameday(w, r)
+		case http.MethodDelete:
+			h.DeleteNameday(w, r)
+		default:
+			methodNotAllowed(w, r, "GET, PUT, DELETE")
+		}
+	default:
+		writeProblem(w, r, newProblem(http.StatusNotFound, "no such resource"))
+	}
 }
 
-func NewMemStore() *MemStore {
-	return &MemStore{
-		data: make(map[string]Nameday),
+// namedayID returns the slug a /nameday/{id} path refers to. The id goes
+// through the same normalization as names on creation, so /nameday/Jānis
+// and /nameday/J%C4%81nis both refer to janis.
+func namedayID(path string) string {
+	matches := NamedayReWithID.FindStringSubmatch(path)
+	if len(matches) < 2 {
+		return ""
 	}
+	return slug.Make(matches[1])
 }
 
-type MemStore struct {
-	data map[string]Nameday
-}
+// readNameday decodes and validates the JSON nameday in the request body.
+// A missing Content-Type is taken to be JSON.
+func readNameday(r *http.Request) (namedays.Nameday, *Problem) {
+	var nameday namedays.Nameday
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			return nameday, newProblem(http.StatusUnsupportedMediaType, "request body must be application/json")
+		}
+	}
 
-func (m *MemStore) Add(name string, nameday Nameday) error {
-	m.data[name] = nameday
-	return nil
-}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxNamedayBody+1))
+	if err != nil {
+		return nameday, newProblem(http.StatusBadRequest, "failed to read request body")
+	}
+	if len(body) > maxNamedayBody {
+		return nameday, newProblem(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must be at most %d bytes", maxNamedayBody))
+	}
 
-func (m *MemStore) Get(name string) (Nameday, error) {
-	nameday, exists := m.data[name]
-	if !exists {
-		return Nameday{}, fmt.Errorf("nameday not found")
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&nameday)
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case err == io.EOF:
+		return nameday, newProblem(http.StatusBadRequest, "request body is empty")
+	case errors.As(err, &typeErr) && typeErr.Field == "":
+		return nameday, newProblem(http.StatusUnprocessableEntity, "request body must be a JSON object")
+	case errors.As(err, &typeErr):
+		return nameday, invalidNameday(FieldError{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return nameday, newProblem(http.StatusBadRequest, "malformed JSON: "+err.Error())
+	case err != nil && strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for DisallowUnknownFields, the
+		// field name is only found in the message
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return nameday, invalidNameday(FieldError{Field: field, Message: "is not a nameday field"})
+	case err != nil:
+		// Well-formed JSON the decoder still rejected, such as an unknown
+		// field should the message above ever change
+		return nameday, newProblem(http.StatusUnprocessableEntity, err.Error())
+	case decoder.More():
+		return nameday, newProblem(http.StatusBadRequest, "request body must hold a single JSON object")
+	}
+
+	if errs := validateNameday(&nameday); len(errs) > 0 {
+		return nameday, invalidNameday(errs...)
 	}
 	return nameday, nil
 }
 
-func (m *MemStore) List() (map[string]Nameday, error) {
-	return m.data, nil
-}
-
-func (m *MemStore) Update(name string, nameday Nameday) error {
-	m.data[name] = nameday
-	return nil
-}
-
-func (m *MemStore) Remove(name string) error {
-	delete(m.data, name)
-	return nil
-}
-
-func NewNamedayHandler(s namedayStore) *NamedayHandler {
-	return &NamedayHandler{store: s}
-}
-
-func (h *NamedayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	switch {
-	case r.Method == http.MethodPost && NamedayRe.MatchString(r.URL.Path):
-		h.CreateNameday(w, r)
-	case r.Method == http.MethodGet && NamedayRe.MatchString(r.URL.Path):
-		h.ListNamedays(w, r)
-	case r.Method == http.MethodGet && NamedayReWithID.MatchString(r.URL.Path):
-		h.GetNameday(w, r)
-	case r.Method == http.MethodPut && NamedayReWithID.MatchString(r.URL.Path):
-		h.UpdateNameday(w, r)
-	case r.Method == http.MethodDelete && NamedayReWithID.MatchString(r.URL.Path):
-		h.DeleteNameday(w, r)
-	default:
-		NotFoundHandler(w, r)
-	}
+// invalidNameday is the 422 problem listing the rejected fields
+func invalidNameday(errs ...FieldError) *Problem {
+	problem := newProblem(http.StatusUnprocessableEntity, "the nameday is invalid")
+	problem.Errors = errs
+	return problem
 }
 
 func (h *NamedayHandler) GetNameday(w http.ResponseWriter, r *http.Request) {
-	matches := NamedayReWithID.FindStringSubmatch(r.URL.Path)
-	if len(matches) < 2 {
-		InternalServerErrorHandler(w, r)
+	id := namedayID(r.URL.Path)
+	if id == "" {
+		writeProblem(w, r, newProblem(http.StatusNotFound, "no such resource"))
 		return
 	}
 
-	nameday, err := h.store.Get(matches[1])
+	country, err := requestCountry(r)
 	if err != nil {
-		NotFoundHandler(w, r)
+		writeProblem(w, r, newProblem(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	jsonBytes, err := json.Marshal(nameday)
-	if err != nil {
-		InternalServerErrorHandler(w, r)
+	nameday, err := h.store.Get(country, id)
+	if errors.Is(err, namedays.NotFoundErr) {
+		writeProblem(w, r, newProblem(http.StatusNotFound, "no nameday for "+id))
+		return
+	} else if err != nil {
+		writeProblem(w, r, newProblem(http.StatusInternalServerError, ""))
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(jsonBytes)
+	writeJSON(w, r, http.StatusOK, nameday)
 }
 
 func (h *NamedayHandler) CreateNameday(w http.ResponseWriter, r *http.Request) {
-	var nameday Nameday
-	if err := json.NewDecoder(r.Body).Decode(&nameday); err != nil {
-		InternalServerErrorHandler(w, r)
+	nameday, problem := readNameday(r)
+	if problem != nil {
+		writeProblem(w, r, problem)
 		return
 	}
 
 	resourceID := slug.Make(nameday.Name)
-	if err := h.store.Add(resourceID, nameday); err != nil {
-		InternalServerErrorHandler(w, r)
+	country := namedayCountry(nameday)
+	if err := h.store.Add(resourceID, nameday); errors.Is(err, namedays.ExistsErr) {
+		// Names differing only in diacritics or case share a slug, such as
+		// Jānis and Janis, so say which name holds it
+		detail := "a nameday for " + resourceID + " already exists"
+		if existing, err := h.store.Get(country, resourceID); err == nil && existing.Name != nameday.Name {
+			detail = fmt.Sprintf("%s has the same slug %s as the existing nameday %s", nameday.Name, resourceID, existing.Name)
+		}
+		writeProblem(w, r, newProblem(http.StatusConflict, detail))
+		return
+	} else if err != nil {
+		writeProblem(w, r, newProblem(http.StatusInternalServerError, ""))
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+
+	markCountryLoaded(country)
+	invalidateSearchIndexes()
+	w.Header().Set("Location", namedayLocation(country, resourceID))
+	writeJSON(w, r, http.StatusCreated, nameday)
 }
 
 func (h *NamedayHandler) UpdateNameday(w http.ResponseWriter, r *http.Request) {
-	matches := NamedayReWithID.FindStringSubmatch(r.URL.Path)
-	if len(matches) < 2 {
-		InternalServerErrorHandler(w, r)
+	id := namedayID(r.URL.Path)
+	if id == "" {
+		writeProblem(w, r, newProblem(http.StatusNotFound, "no such resource"))
 		return
 	}
 
-	var nameday Nameday
-	if err := json.NewDecoder(r.Body).Decode(&nameday); err != nil {
-		InternalServerErrorHandler(w, r)
+	nameday, problem := readNameday(r)
+	if problem != nil {
+		writeProblem(w, r, problem)
+		return
+	}
+	// A nameday must stay reachable by its name and in its calendar
+	if slug.Make(nameday.Name) != id {
+		writeProblem(w, r, invalidNameday(FieldError{Field: "name", Message: "must have the slug " + id}))
+		return
+	}
+	country, err := requestCountry(r)
+	if err != nil {
+		writeProblem(w, r, newProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if nameday.Country != "" && nameday.Country != country {
+		writeProblem(w, r, invalidNameday(FieldError{Field: "country", Message: "must be " + country}))
 		return
 	}
+	nameday.Country = country
 
-	if err := h.store.Update(matches[1], nameday); err != nil {
-		InternalServerErrorHandler(w, r)
+	if err := h.store.Update(country, id, nameday); errors.Is(err, namedays.NotFoundErr) {
+		writeProblem(w, r, newProblem(http.StatusNotFound, "no nameday for "+id))
+		return
+	} else if err != nil {
+		writeProblem(w, r, newProblem(http.StatusInternalServerError, ""))
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	invalidateSearchIndexes()
+	writeJSON(w, r, http.StatusOK, nameday)
 }
 
 func (h *NamedayHandler) ListNamedays(w http.ResponseWriter, r *http.Request) {
-	namedaysList, err := h.store.List()
+	namedaysList, err := h.store.List(strings.ToLower(r.URL.Query().Get("country")))
 	if err != nil {
-		InternalServerErrorHandler(w, r)
+		writeProblem(w, r, newProblem(http.StatusInternalServerError, ""))
 		return
 	}
 
-	jsonBytes, _ := json.Marshal(namedaysList)
-	w.WriteHeader(http.StatusOK)
-	w.Write(jsonBytes)
+	writeJSON(w, r, http.StatusOK, namedaysList)
 }
 
 func (h *NamedayHandler) DeleteNameday(w http.ResponseWriter, r *http.Request) {
-	matches := NamedayReWithID.FindStringSubmatch(r.URL.Path)
-	if len(matches) < 2 {
-		InternalServerErrorHandler(w, r)
+	id := namedayID(r.URL.Path)
+	if id == "" {
+		writeProblem(w, r, newProblem(http.StatusNotFound, "no such resource"))
 		return
 	}
 
-	if err := h.store.Remove(matches[1]); err != nil {
-		InternalServerErrorHandler(w, r)
+	country, err := requestCountry(r)
+	if err != nil {
+		writeProblem(w, r, newProblem(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	if err := h.store.Remove(country, id); errors.Is(err, namedays.NotFoundErr) {
+		writeProblem(w, r, newProblem(http.StatusNotFound, "no nameday for "+id))
+		return
+	} else if err != nil {
+		writeProblem(w, r, newProblem(http.StatusInternalServerError, ""))
+		return
+	}
+
+	invalidateSearchIndexes()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// namedayLocation is the URL of a nameday. It names the country so that
+// the Accept-Language of whoever follows it cannot pick another calendar.
+func namedayLocation(country, id string) string {
+	return "/nameday/" + id + "?country=" + country
+}
+
+// namedayCountry returns the calendar a nameday belongs to, which is the
+// default country for namedays created without one
+func namedayCountry(nameday namedays.Nameday) string {
+	if nameday.Country == "" {
+		return defaultCountry
+	}
+	return nameday.Country
 }
 
 func InternalServerErrorHandler(w http.ResponseWriter, r *http.Request) {
@@ -401,11 +574,12 @@ func InternalServerErrorHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("500 Internal Server Error"))
 }
 
+func BadRequestHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte("400 Bad Request"))
+}
+
 func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotFound)
 	w.Write([]byte("404 Not Found"))
 }
-
-func GetCurrentMonthDate() string {
-	return time.Now().Format("01-02")
-}
@@ -7,22 +7,25 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
-	"time"
+
+	"github.com/zrks/namedays/pkg/migrations"
+	"github.com/zrks/namedays/pkg/namedays"
 )
 
 const (
 	errFailedToCreateRequest = "Failed to create request: %v"
-	testPersonKey            = "test-person"
 	testJohnSmith            = "John Smith"
+	testJohnSmithValid       = "John-Smith" // passes nameday validation
 	johnSmithKey             = "john-smith"
 	johnSmithPath            = "/nameday/" + johnSmithKey
 	errWrongStatusCode       = "Handler returned wrong status code: got %v want %v"
 )
 
 // Helper functions to reduce duplication
-func createTestNamedayHandler() (*MemStore, *NamedayHandler) {
-	store := NewMemStore()
+func createTestNamedayHandler() (*namedays.MemStore, *NamedayHandler) {
+	store := namedays.NewMemStore(defaultCountry)
 	handler := NewNamedayHandler(store)
 	return store, handler
 }
@@ -50,15 +53,9 @@ func createTestDb(t *testing.T) (string, *sql.DB) {
 		t.Fatal("Failed to open database:", err)
 	}
 
-	// Create table
-	_, err = db.Exec(`
-	CREATE TABLE IF NOT EXISTS namedays (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		date TEXT NOT NULL,
-		name TEXT NOT NULL
-	);`)
-	if err != nil {
-		t.Fatal("Failed to create table:", err)
+	// Create schema
+	if err = migrations.Up(db); err != nil {
+		t.Fatal("Failed to migrate database:", err)
 	}
 
 	// Register cleanup
@@ -70,8 +67,8 @@ func createTestDb(t *testing.T) (string, *sql.DB) {
 	return tmpDBPath, db
 }
 
-func addTestNameday(store *MemStore, key string, name, date string) Nameday {
-	nameday := Nameday{
+func addTestNameday(store *namedays.MemStore, key string, name, date string) namedays.Nameday {
+	nameday := namedays.Nameday{
 		Name: name,
 		Date: date,
 	}
@@ -89,8 +86,8 @@ func TestNamedayHandlerCreateNameday(t *testing.T) {
 	store, handler := createTestNamedayHandler()
 
 	// Test data
-	nameday := Nameday{
-		Name: testJohnSmith,
+	nameday := namedays.Nameday{
+		Name: testJohnSmithValid,
 		Date: "04-12",
 	}
 	jsonData, _ := json.Marshal(nameday)
@@ -100,10 +97,16 @@ func TestNamedayHandlerCreateNameday(t *testing.T) {
 	handler.ServeHTTP(rr, req)
 
 	// Check response
-	checkResponseStatus(t, rr, http.StatusOK)
+	checkResponseStatus(t, rr, http.StatusCreated)
+	if location := rr.Header().Get("Location"); location != johnSmithPath+"?country=lv" {
+		t.Errorf("Expected Location %s?country=lv, got %q", johnSmithPath, location)
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", contentType)
+	}
 
 	// Verify data was stored correctly
-	storedNameday, err := store.Get(johnSmithKey)
+	storedNameday, err := store.Get("", johnSmithKey)
 	if err != nil {
 		t.Fatalf("Failed to retrieve created nameday: %v", err)
 	}
@@ -126,7 +129,7 @@ func TestNamedayHandlerGetNameday(t *testing.T) {
 	checkResponseStatus(t, rr, http.StatusOK)
 
 	// Verify response data
-	var responseNameday Nameday
+	var responseNameday namedays.Nameday
 	if err := json.Unmarshal(rr.Body.Bytes(), &responseNameday); err != nil {
 		t.Fatalf("Failed to unmarshal response: %v", err)
 	}
@@ -153,8 +156,8 @@ func TestNamedayHandlerUpdateNameday(t *testing.T) {
 	addTestNameday(store, johnSmithKey, testJohnSmith, "04-12")
 
 	// Updated data
-	updatedNameday := Nameday{
-		Name: testJohnSmith,
+	updatedNameday := namedays.Nameday{
+		Name: testJohnSmithValid,
 		Date: "05-15", // Changed date
 	}
 	jsonData, _ := json.Marshal(updatedNameday)
@@ -167,7 +170,7 @@ func TestNamedayHandlerUpdateNameday(t *testing.T) {
 	checkResponseStatus(t, rr, http.StatusOK)
 
 	// Verify data was updated correctly
-	storedNameday, err := store.Get(johnSmithKey)
+	storedNameday, err := store.Get("", johnSmithKey)
 	if err != nil {
 		t.Fatalf("Failed to retrieve updated nameday: %v", err)
 	}
@@ -187,15 +190,182 @@ func TestNamedayHandlerDeleteNameday(t *testing.T) {
 	handler.ServeHTTP(rr, req)
 
 	// Check response
-	checkResponseStatus(t, rr, http.StatusOK)
+	checkResponseStatus(t, rr, http.StatusNoContent)
+	if rr.Body.Len() != 0 {
+		t.Errorf("Expected an empty body, got %q", rr.Body.String())
+	}
 
 	// Verify data was deleted
-	_, err := store.Get(johnSmithKey)
+	_, err := store.Get("", johnSmithKey)
 	if err == nil {
 		t.Errorf("Nameday was not deleted as expected")
 	}
 }
 
+func TestNamedayHandlerCreateExisting(t *testing.T) {
+	store, handler := createTestNamedayHandler()
+	addTestNameday(store, johnSmithKey, testJohnSmith, "04-12")
+
+	jsonData, _ := json.Marshal(namedays.Nameday{Name: testJohnSmithValid, Date: "05-15"})
+	rr, req := setupTestRequest(t, http.MethodPost, "/nameday", jsonData)
+	handler.ServeHTTP(rr, req)
+
+	// Creating an existing nameday must not overwrite it
+	checkResponseStatus(t, rr, http.StatusConflict)
+	if stored, _ := store.Get("", johnSmithKey); stored.Date != "04-12" {
+		t.Errorf("Expected the existing nameday to be kept, got %v", stored)
+	}
+}
+
+func TestNamedayHandlerMissingNameday(t *testing.T) {
+	store, handler := createTestNamedayHandler()
+
+	jsonData, _ := json.Marshal(namedays.Nameday{Name: testJohnSmithValid, Date: "05-15"})
+	rr, req := setupTestRequest(t, http.MethodPut, johnSmithPath, jsonData)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusNotFound)
+	if _, err := store.Get("", johnSmithKey); err == nil {
+		t.Error("Expected update not to create the nameday")
+	}
+
+	rr, req = setupTestRequest(t, http.MethodDelete, johnSmithPath, nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusNotFound)
+}
+
+func TestNamedayHandlerSingleWordSlug(t *testing.T) {
+	store, handler := createTestNamedayHandler()
+
+	rr, req := setupTestRequest(t, http.MethodPost, "/nameday", []byte(`{"name": "Anna", "date": "07-26"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusCreated)
+	if location := rr.Header().Get("Location"); location != "/nameday/anna?country=lv" {
+		t.Fatalf("Expected Location /nameday/anna?country=lv, got %q", location)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/nameday/anna", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+
+	rr, req = setupTestRequest(t, http.MethodPut, "/nameday/anna", []byte(`{"name": "Anna", "date": "12-09"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	if stored, _ := store.Get("", "anna"); stored.Date != "12-09" {
+		t.Errorf("Expected anna to be updated, got %v", stored)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodDelete, "/nameday/anna", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusNoContent)
+}
+
+func TestNamedayHandlerOtherCountry(t *testing.T) {
+	addTestCountries(t)
+	store, handler := createTestNamedayHandler()
+	addTestNameday(store, "ona", "Ona", "02-01")
+
+	rr, req := setupTestRequest(t, http.MethodPost, "/nameday", []byte(`{"name": "Ona", "date": "01-01", "country": "lt"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusCreated)
+	location := rr.Header().Get("Location")
+	if location != "/nameday/ona?country=lt" {
+		t.Fatalf("Expected Location /nameday/ona?country=lt, got %q", location)
+	}
+
+	// The Location reaches the Lithuanian nameday, the bare path the
+	// Latvian one
+	rr, req = setupTestRequest(t, http.MethodGet, location, nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	if !strings.Contains(rr.Body.String(), `"date":"01-01"`) {
+		t.Errorf("Expected the lt nameday, got %s", rr.Body.String())
+	}
+	rr, req = setupTestRequest(t, http.MethodGet, "/nameday/ona", nil)
+	handler.ServeHTTP(rr, req)
+	if !strings.Contains(rr.Body.String(), `"date":"02-01"`) {
+		t.Errorf("Expected the lv nameday, got %s", rr.Body.String())
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/nameday?country=lt", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	var list map[string]namedays.Nameday
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil || len(list) != 1 || list["ona"].Date != "01-01" {
+		t.Errorf("Expected the list in lt to hold Ona, got %s", rr.Body.String())
+	}
+
+	rr, req = setupTestRequest(t, http.MethodPut, location, []byte(`{"name": "Ona", "date": "01-02", "country": "lv"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusUnprocessableEntity)
+
+	rr, req = setupTestRequest(t, http.MethodPut, location, []byte(`{"name": "Ona", "date": "01-02"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	if stored, _ := store.Get("lt", "ona"); stored.Date != "01-02" || stored.Country != "lt" {
+		t.Errorf("Expected the lt nameday to be updated, got %v", stored)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodDelete, location, nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusNoContent)
+	if _, err := store.Get("lt", "ona"); err == nil {
+		t.Error("Expected the lt nameday to be deleted")
+	}
+	if stored, _ := store.Get("lv", "ona"); stored.Date != "02-01" {
+		t.Errorf("Expected the lv nameday to be kept, got %v", stored)
+	}
+}
+
+func TestNamedayHandlerUnicodeID(t *testing.T) {
+	store, handler := createTestNamedayHandler()
+	addTestNameday(store, "janis", "Jānis", "06-24")
+
+	// Names resolve to the slug they were created under
+	for _, path := range []string{"/nameday/janis", "/nameday/J%C4%81nis", "/nameday/JANIS", "/nameday/J%C4%81NIS"} {
+		rr, req := setupTestRequest(t, http.MethodGet, path, nil)
+		handler.ServeHTTP(rr, req)
+		checkResponseStatus(t, rr, http.StatusOK)
+
+		var responseNameday namedays.Nameday
+		if err := json.Unmarshal(rr.Body.Bytes(), &responseNameday); err != nil || responseNameday.Name != "Jānis" {
+			t.Errorf("Expected %s to return Jānis, got %s", path, rr.Body.String())
+		}
+	}
+
+	// Paths without any letter or digit have no slug
+	rr, req := setupTestRequest(t, http.MethodGet, "/nameday/%21%21", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusNotFound)
+}
+
+func TestNamedayHandlerSlugCollision(t *testing.T) {
+	store, handler := createTestNamedayHandler()
+	addTestNameday(store, "janis", "Jānis", "06-24")
+
+	rr, req := setupTestRequest(t, http.MethodPost, "/nameday", []byte(`{"name": "Janis", "date": "01-01"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusConflict)
+	if problem := readTestProblem(t, rr); !strings.Contains(problem.Detail, "Jānis") {
+		t.Errorf("Expected the problem to name the existing nameday, got %q", problem.Detail)
+	}
+	if stored, _ := store.Get("", "janis"); stored.Name != "Jānis" || stored.Date != "06-24" {
+		t.Errorf("Expected Jānis to be kept, got %v", stored)
+	}
+
+	// Updating under a name with another slug would make it unreachable
+	rr, req = setupTestRequest(t, http.MethodPut, "/nameday/janis", []byte(`{"name": "Anna", "date": "07-26"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusUnprocessableEntity)
+
+	// Spelling variants of the same name are an update
+	rr, req = setupTestRequest(t, http.MethodPut, "/nameday/J%C4%81nis", []byte(`{"name": "Janis", "date": "06-24"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	if stored, _ := store.Get("", "janis"); stored.Name != "Janis" {
+		t.Errorf("Expected the name to be updated, got %v", stored)
+	}
+}
+
 func TestNamedayHandlerListNamedays(t *testing.T) {
 	store, handler := createTestNamedayHandler()
 
@@ -211,7 +381,7 @@ func TestNamedayHandlerListNamedays(t *testing.T) {
 	checkResponseStatus(t, rr, http.StatusOK)
 
 	// Verify response data
-	var responseNamedays map[string]Nameday
+	var responseNamedays map[string]namedays.Nameday
 	if err := json.Unmarshal(rr.Body.Bytes(), &responseNamedays); err != nil {
 		t.Fatalf("Failed to unmarshal response: %v", err)
 	}
@@ -233,8 +403,18 @@ func TestNamedayHandlerInvalidMethod(t *testing.T) {
 	rr, req := setupTestRequest(t, http.MethodPatch, johnSmithPath, nil)
 	handler.ServeHTTP(rr, req)
 
-	// Check response - should be 404 (not found for invalid method)
-	checkResponseStatus(t, rr, http.StatusNotFound)
+	// Check response - should be 405 with the supported methods
+	checkResponseStatus(t, rr, http.StatusMethodNotAllowed)
+	if allow := rr.Header().Get("Allow"); allow != "GET, PUT, DELETE" {
+		t.Errorf("Expected Allow GET, PUT, DELETE, got %q", allow)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodDelete, "/nameday", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusMethodNotAllowed)
+	if allow := rr.Header().Get("Allow"); allow != "GET, POST" {
+		t.Errorf("Expected Allow GET, POST, got %q", allow)
+	}
 }
 
 func TestReadJSONFromURL(t *testing.T) {
@@ -272,6 +452,8 @@ func TestFilterNamedaysByMonth(t *testing.T) {
 	januaryResults := FilterNamedaysByMonth(testData, "01")
 	if len(januaryResults) != 2 {
 		t.Errorf("Expected 2 namedays in January, got %d", len(januaryResults))
+	} else if januaryResults[0] != "01-01: New Year" || januaryResults[1] != "01-15: Mid January" {
+		t.Errorf("Expected January namedays in date order, got %v", januaryResults)
 	}
 
 	// Test filtering for February (02)
@@ -287,24 +469,6 @@ func TestFilterNamedaysByMonth(t *testing.T) {
 	}
 }
 
-func TestGetCurrentMonth(t *testing.T) {
-	// Current month should match what time.Now() returns
-	expected := time.Now().Format("01")
-	result := GetCurrentMonth()
-	if result != expected {
-		t.Errorf("GetCurrentMonth() returned %s, expected %s", result, expected)
-	}
-}
-
-func TestGetCurrentMonthDate(t *testing.T) {
-	// Current month-date should match what time.Now() returns
-	expected := time.Now().Format("01-02")
-	result := GetCurrentMonthDate()
-	if result != expected {
-		t.Errorf("GetCurrentMonthDate() returned %s, expected %s", result, expected)
-	}
-}
-
 func TestRenderHTMLList(t *testing.T) {
 	testItems := []string{"Item 1", "Item 2"}
 	result := RenderHTMLList(testItems)
@@ -328,67 +492,12 @@ func TestRenderHTMLList(t *testing.T) {
 	}
 }
 
-func TestMemStore(t *testing.T) {
-	store := NewMemStore()
-
-	// Test Add and Get
-	nameday := Nameday{Name: "Test Person", Date: "05-05"}
-	err := store.Add(testPersonKey, nameday)
-	if err != nil {
-		t.Fatalf("Failed to add nameday: %v", err)
-	}
-
-	retrieved, err := store.Get(testPersonKey)
-	if err != nil {
-		t.Fatalf("Failed to get nameday: %v", err)
-	}
-	if retrieved.Name != nameday.Name || retrieved.Date != nameday.Date {
-		t.Errorf("Retrieved nameday does not match: got %v want %v", retrieved, nameday)
-	}
-
-	// Test List
-	namedaysList, err := store.List()
-	if err != nil {
-		t.Fatalf("Failed to list namedays: %v", err)
-	}
-	if len(namedaysList) != 1 {
-		t.Errorf("Expected 1 nameday in list, got %d", len(namedaysList))
-	}
-
-	// Test Update
-	updatedNameday := Nameday{Name: "Test Person Updated", Date: "06-06"}
-	err = store.Update(testPersonKey, updatedNameday)
-	if err != nil {
-		t.Fatalf("Failed to update nameday: %v", err)
-	}
-
-	retrieved, err = store.Get(testPersonKey)
-	if err != nil {
-		t.Fatalf("Failed to get updated nameday: %v", err)
-	}
-	if retrieved.Name != updatedNameday.Name || retrieved.Date != updatedNameday.Date {
-		t.Errorf("Updated nameday does not match: got %v want %v", retrieved, updatedNameday)
-	}
-
-	// Test Remove
-	err = store.Remove(testPersonKey)
-	if err != nil {
-		t.Fatalf("Failed to remove nameday: %v", err)
-	}
-
-	_, err = store.Get(testPersonKey)
-	if err == nil {
-		t.Errorf("Expected error when getting removed nameday")
-	}
-}
-
 // TestHomeHandler tests the home page handler
 func TestHomeHandler(t *testing.T) {
 	tmpDBPath, db := createTestDb(t)
 
-	// Insert test data with today's date
-	today := time.Now().Format("01-02")
-	_, err := db.Exec("INSERT INTO namedays (date, name) VALUES (?, ?)", today, "Test Name")
+	// Insert test data for the day of the handler's clock
+	_, err := db.Exec("INSERT INTO namedays (date, name) VALUES (?, ?)", "06-24", "Test Name")
 	if err != nil {
 		t.Fatal("Failed to insert test data:", err)
 	}
@@ -398,6 +507,7 @@ func TestHomeHandler(t *testing.T) {
 
 	// Use the new constructor with our test DB path
 	handler := NewHomeHandler(tmpDBPath)
+	handler.clock = fixedClock(midsummer)
 
 	// Call the handler
 	handler.ServeHTTP(rr, req)
@@ -405,27 +515,29 @@ func TestHomeHandler(t *testing.T) {
 	// Check response code
 	checkResponseStatus(t, rr, http.StatusOK)
 
-	// Check that the response contains HTML
+	// Check that the response contains HTML and today's name
 	if !bytes.Contains(rr.Body.Bytes(), []byte("<!DOCTYPE html>")) {
 		t.Error("Response does not contain HTML")
 	}
+	if !bytes.Contains(rr.Body.Bytes(), []byte("Test Name")) {
+		t.Error("Response does not contain today's name")
+	}
 }
 
-func TestGetNamedayDB(t *testing.T) {
+func TestGetNamedaysForDateDB(t *testing.T) {
 	_, db := createTestDb(t)
 
-	// Insert test data
-	today := time.Now().Format("01-02")
+	// Insert test data without a country, which belongs to the default one
 	testName := "Today's Test Name"
-	_, err := db.Exec("INSERT INTO namedays (date, name) VALUES (?, ?)", today, testName)
+	_, err := db.Exec("INSERT INTO namedays (date, name) VALUES (?, ?)", "06-24", testName)
 	if err != nil {
 		t.Fatal("Failed to insert test data:", err)
 	}
 
 	// Call the function
-	names, err := getNameday(db)
+	names, err := getNamedaysForDate(db, defaultCountry, "06-24")
 	if err != nil {
-		t.Fatal("getNameday returned an error:", err)
+		t.Fatal("getNamedaysForDate returned an error:", err)
 	}
 
 	// Verify the result
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const DefaultMessageFormat = "json"
+
+// MessageFormats renders a webhook payload into the message body expected
+// by each supported chat service
+var MessageFormats = map[string]func(payload *WebhookPayload) interface{}{
+	"json":       func(payload *WebhookPayload) interface{} { return payload },
+	"slack":      renderSlack,
+	"teams":      renderTeams,
+	"mattermost": renderMattermost,
+	"discord":    renderDiscord,
+}
+
+// messageFormatNames lists the supported formats for error messages
+func messageFormatNames() string {
+	names := make([]string, 0, len(MessageFormats))
+	for name := range MessageFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// RenderMessage renders a payload in the named format
+func RenderMessage(format string, payload *WebhookPayload) (interface{}, error) {
+	if format == "" {
+		format = DefaultMessageFormat
+	}
+	render, ok := MessageFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported message format %q: expected one of %s", format, messageFormatNames())
+	}
+	return render(payload), nil
+}
+
+// messageTitle is the heading shared by every chat format
+func messageTitle(payload *WebhookPayload) string {
+	title := "Namedays"
+	if t, err := time.Parse("01-02", payload.Date); err == nil {
+		title += " on " + t.Format("2 January")
+	}
+	return title + " (" + strings.ToUpper(payload.Country) + ")"
+}
+
+// messageContacts lists the full names of the celebrating contacts
+func messageContacts(payload *WebhookPayload) []string {
+	lines := make([]string, 0, len(payload.Contacts))
+	for _, c := range payload.Contacts {
+		lines = append(lines, c.Name)
+	}
+	return lines
+}
+
+// plainMessage is the text fallback used by notifications and clients
+// that cannot show rich messages
+func plainMessage(payload *WebhookPayload) string {
+	text := messageTitle(payload) + ": "
+	if len(payload.Names) == 0 {
+		text += "no namedays today"
+	} else {
+		text += strings.Join(payload.Names, ", ")
+	}
+	if len(payload.Contacts) > 0 {
+		text += ". Celebrating: " + strings.Join(messageContacts(payload), ", ")
+	}
+	return text
+}
+
+// markdownNames formats names for Markdown flavours escaped with escape
+func markdownNames(names []string, escape func(string) string) string {
+	if len(names) == 0 {
+		return "_No namedays today_"
+	}
+	bold := make([]string, 0, len(names))
+	for _, name := range names {
+		bold = append(bold, "**"+escape(name)+"**")
+	}
+	return strings.Join(bold, ", ")
+}
+
+var (
+	slackEscaper    = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`, ">", `\>`, "#", `\#`, "[", `\[`, "]", `\]`)
+)
+
+// renderSlack builds a Slack Block Kit message
+func renderSlack(payload *WebhookPayload) interface{} {
+	names := "_No namedays today_"
+	if len(payload.Names) > 0 {
+		bold := make([]string, 0, len(payload.Names))
+		for _, name := range payload.Names {
+			bold = append(bold, "*"+slackEscaper.Replace(name)+"*")
+		}
+		names = strings.Join(bold, ", ")
+	}
+
+	blocks := []map[string]interface{}{
+		{"type": "header", "text": map[string]interface{}{"type": "plain_text", "text": messageTitle(payload)}},
+		{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": names}},
+	}
+	if len(payload.Contacts) > 0 {
+		contacts := make([]string, 0, len(payload.Contacts))
+		for _, name := range messageContacts(payload) {
+			contacts = append(contacts, "• "+slackEscaper.Replace(name))
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": "*Celebrating today*\n" + strings.Join(contacts, "\n")},
+		})
+	}
+
+	// The fallback text is shown in notifications and is parsed as mrkdwn
+	// too, so a name such as <!channel> must not reach it unescaped
+	return map[string]interface{}{
+		"text":   slackEscaper.Replace(plainMessage(payload)),
+		"blocks": blocks,
+	}
+}
+
+// renderTeams builds a Microsoft Teams message carrying an Adaptive Card
+func renderTeams(payload *WebhookPayload) interface{} {
+	names := "No namedays today"
+	if len(payload.Names) > 0 {
+		names = strings.Join(payload.Names, ", ")
+	}
+
+	body := []map[string]interface{}{
+		{"type": "TextBlock", "text": messageTitle(payload), "size": "Large", "weight": "Bolder", "wrap": true},
+		{"type": "TextBlock", "text": names, "wrap": true},
+	}
+	if len(payload.Contacts) > 0 {
+		facts := make([]map[string]string, 0, len(payload.Contacts))
+		for _, c := range payload.Contacts {
+			facts = append(facts, map[string]string{"title": c.FirstName, "value": c.Name})
+		}
+		body = append(body,
+			map[string]interface{}{"type": "TextBlock", "text": "Celebrating today", "weight": "Bolder", "wrap": true},
+			map[string]interface{}{"type": "FactSet", "facts": facts},
+		)
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]interface{}{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
+
+// renderMattermost builds a Mattermost incoming webhook message
+func renderMattermost(payload *WebhookPayload) interface{} {
+	text := "#### " + markdownEscaper.Replace(messageTitle(payload)) + "\n" + markdownNames(payload.Names, markdownEscaper.Replace)
+	if len(payload.Contacts) > 0 {
+		text += "\n\n**Celebrating today**"
+		for _, name := range messageContacts(payload) {
+			text += "\n- " + markdownEscaper.Replace(name)
+		}
+	}
+	return map[string]interface{}{
+		"username": "Namedays",
+		"text":     text,
+	}
+}
+
+// renderDiscord builds a Discord webhook message with a single embed
+func renderDiscord(payload *WebhookPayload) interface{} {
+	embed := map[string]interface{}{
+		"title":       messageTitle(payload),
+		"description": markdownNames(payload.Names, markdownEscaper.Replace),
+		"color":       0x9e3039,
+	}
+	if len(payload.Contacts) > 0 {
+		contacts := make([]string, 0, len(payload.Contacts))
+		for _, name := range messageContacts(payload) {
+			contacts = append(contacts, markdownEscaper.Replace(name))
+		}
+		embed["fields"] = []map[string]interface{}{
+			{"name": "Celebrating today", "value": strings.Join(contacts, "\n")},
+		}
+	}
+	return map[string]interface{}{
+		"username": "Namedays",
+		"content":  markdownEscaper.Replace(plainMessage(payload)),
+		"embeds":   []map[string]interface{}{embed},
+	}
+}
+
+type messagePreviewHandler struct {
+	db    *sql.DB
+	clock Clock
+}
+
+func NewMessagePreviewHandler(db *sql.DB) *messagePreviewHandler {
+	return &messagePreviewHandler{db: db, clock: systemClock{}}
+}
+
+// ServeHTTP renders today's message in the requested format without
+// sending it anywhere
+func (h *messagePreviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	now, err := requestNow(h.clock, r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	list := r.URL.Query().Get("list")
+	if list != "" && !ContactListRe.MatchString(list) {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	payload, err := buildWebhookPayload(h.db, country, list, now)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	message, err := RenderMessage(r.URL.Query().Get("format"), payload)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, message)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var testPayload = &WebhookPayload{
+	Event:   WebhookEvent,
+	Country: "lv",
+	Day:     "2024-06-24",
+	Date:    "06-24",
+	Names:   []string{"Jānis", "<Ants>"},
+	List:    "team",
+	Contacts: []ContactMatch{
+		{Name: "Jānis Bērziņš", FirstName: "Jānis", Dates: []string{"06-24"}},
+	},
+}
+
+// renderTestMessage renders the test payload and decodes it back into
+// generic JSON
+func renderTestMessage(t *testing.T, format string, payload *WebhookPayload) map[string]interface{} {
+	message, err := RenderMessage(format, payload)
+	if err != nil {
+		t.Fatalf("RenderMessage(%q) returned an error: %v", format, err)
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("Failed to marshal %s message: %v", format, err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("Failed to unmarshal %s message: %v", format, err)
+	}
+	return result
+}
+
+func TestRenderSlack(t *testing.T) {
+	message := renderTestMessage(t, "slack", testPayload)
+
+	if text := message["text"]; text != "Namedays on 24 June (LV): Jānis, &lt;Ants&gt;. Celebrating: Jānis Bērziņš" {
+		t.Errorf("Unexpected fallback text: %v", text)
+	}
+	blocks, _ := message["blocks"].([]interface{})
+	if len(blocks) != 3 {
+		t.Fatalf("Expected header, names and contacts blocks, got %v", blocks)
+	}
+	names := blocks[1].(map[string]interface{})["text"].(map[string]interface{})["text"]
+	if names != "*Jānis*, *&lt;Ants&gt;*" {
+		t.Errorf("Expected escaped bold names, got %v", names)
+	}
+}
+
+func TestRenderSlackEscapesMentions(t *testing.T) {
+	payload := &WebhookPayload{Country: "lv", Date: "06-24", Names: []string{"Jānis"}, Contacts: []ContactMatch{{Name: "<!channel>"}}}
+	message := renderTestMessage(t, "slack", payload)
+
+	var body strings.Builder
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(message); err != nil {
+		t.Fatalf("Failed to encode message: %v", err)
+	}
+	if strings.Contains(body.String(), "<!channel>") {
+		t.Errorf("Expected every mention to be escaped, got %s", body.String())
+	}
+	if text := message["text"]; text != "Namedays on 24 June (LV): Jānis. Celebrating: &lt;!channel&gt;" {
+		t.Errorf("Unexpected fallback text: %v", text)
+	}
+}
+
+func TestRenderTeams(t *testing.T) {
+	message := renderTestMessage(t, "teams", testPayload)
+
+	attachments, _ := message["attachments"].([]interface{})
+	if message["type"] != "message" || len(attachments) != 1 {
+		t.Fatalf("Expected a message with one attachment, got %v", message)
+	}
+	attachment := attachments[0].(map[string]interface{})
+	if attachment["contentType"] != "application/vnd.microsoft.card.adaptive" {
+		t.Errorf("Unexpected content type: %v", attachment["contentType"])
+	}
+	card := attachment["content"].(map[string]interface{})
+	if card["type"] != "AdaptiveCard" || len(card["body"].([]interface{})) != 4 {
+		t.Errorf("Unexpected card: %v", card)
+	}
+}
+
+func TestRenderMattermost(t *testing.T) {
+	message := renderTestMessage(t, "mattermost", testPayload)
+
+	expected := "#### Namedays on 24 June (LV)\n**Jānis**, **<Ants\\>**\n\n**Celebrating today**\n- Jānis Bērziņš"
+	if text := message["text"]; text != expected {
+		t.Errorf("Unexpected text: %q", text)
+	}
+}
+
+func TestRenderDiscord(t *testing.T) {
+	message := renderTestMessage(t, "discord", &WebhookPayload{Country: "lv", Date: "02-29", Names: []string{}})
+
+	embeds, _ := message["embeds"].([]interface{})
+	if len(embeds) != 1 {
+		t.Fatalf("Expected a single embed, got %v", message)
+	}
+	embed := embeds[0].(map[string]interface{})
+	if embed["title"] != "Namedays on 29 February (LV)" || embed["description"] != "_No namedays today_" {
+		t.Errorf("Unexpected embed: %v", embed)
+	}
+	if _, ok := embed["fields"]; ok {
+		t.Errorf("Did not expect contact fields without contacts: %v", embed)
+	}
+}
+
+func TestRenderMessageUnknownFormat(t *testing.T) {
+	if _, err := RenderMessage("telex", testPayload); err == nil || !strings.Contains(err.Error(), "slack") {
+		t.Errorf("Expected an error listing the supported formats, got %v", err)
+	}
+
+	message := renderTestMessage(t, "", testPayload)
+	if message["event"] != WebhookEvent {
+		t.Errorf("Expected the plain JSON payload by default, got %v", message)
+	}
+}
+
+func TestMessagePreviewHandler(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "06-24", "Jānis")
+	handler := NewMessagePreviewHandler(db)
+	handler.clock = fixedClock(midsummer)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/today/message?format=slack", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	if body := rr.Body.String(); !strings.Contains(body, `"blocks"`) || !strings.Contains(body, "*Jānis*") {
+		t.Errorf("Expected a Slack message for Jānis, got %s", body)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/api/v1/today/message?format=telex", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusBadRequest)
+}
+
+func TestWebhookDeliveryUsesFormat(t *testing.T) {
+	scheduler, receiver, url := createTestScheduler(t)
+	hook := addTestWebhook(t, scheduler, Webhook{URL: url, Format: "Discord"})
+	if hook.Format != "discord" {
+		t.Errorf("Expected the format to be normalised, got %q", hook.Format)
+	}
+
+	if _, err := scheduler.Deliver(context.Background(), hook, midsummer); err != nil {
+		t.Fatalf("Deliver returned an error: %v", err)
+	}
+	if body := string(receiver.bodies[0]); !strings.Contains(body, `"embeds"`) {
+		t.Errorf("Expected a Discord message, got %s", body)
+	}
+}
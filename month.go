@@ -0,0 +1,224 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+var (
+	MonthRe     = regexp.MustCompile(`^/api/v1/month/([^/]+)$`)
+	MonthPageRe = regexp.MustCompile(`^/month/([^/]*)$`)
+	monthNumRe  = regexp.MustCompile(`^(0[1-9]|1[0-2])$`)
+)
+
+// MonthDay is the names celebrated on one day of a month
+type MonthDay struct {
+	Date  string   `json:"date"`
+	Names []string `json:"names"`
+}
+
+// MonthNamedays is the JSON representation of a month of namedays, with
+// one entry per day in date order
+type MonthNamedays struct {
+	Country string     `json:"country"`
+	Month   string     `json:"month"`
+	Days    []MonthDay `json:"days"`
+}
+
+// resolveMonth turns "MM" or "current" into a two-digit month
+func resolveMonth(s string, now time.Time) (string, error) {
+	if s == "current" || s == "" {
+		return now.Format("01"), nil
+	}
+	if !monthNumRe.MatchString(s) {
+		return "", fmt.Errorf("invalid month %q: expected MM", s)
+	}
+	return s, nil
+}
+
+// daysInMonth counts the days of a month in a leap year, so February
+// always includes 02-29
+func daysInMonth(month string) int {
+	t, _ := time.Parse("2006-01", "2000-"+month)
+	return t.AddDate(0, 1, -1).Day()
+}
+
+// getNamedaysForMonth returns every day of a month in a country's calendar,
+// including days without names
+func getNamedaysForMonth(db *sql.DB, country, month string) ([]MonthDay, error) {
+	named, err := getNamedaysForRange(db, country, month+"-01", month+"-31")
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string][]string, len(named))
+	for _, day := range named {
+		byDate[day.Date] = day.Names
+	}
+
+	days := make([]MonthDay, 0, 31)
+	for day := 1; day <= daysInMonth(month); day++ {
+		date := fmt.Sprintf("%s-%02d", month, day)
+		names := byDate[date]
+		if names == nil {
+			names = []string{}
+		}
+		days = append(days, MonthDay{Date: date, Names: names})
+	}
+	return days, nil
+}
+
+type monthHandler struct {
+	db    *sql.DB
+	clock Clock
+}
+
+func NewMonthHandler(db *sql.DB) *monthHandler {
+	return &monthHandler{db: db, clock: systemClock{}}
+}
+
+func (h *monthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	matches := MonthRe.FindStringSubmatch(r.URL.Path)
+	if r.Method != http.MethodGet || len(matches) < 2 {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	now, err := requestNow(h.clock, r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	month, err := resolveMonth(matches[1], now)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	days, err := getNamedaysForMonth(h.db, country, month)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, MonthNamedays{Country: country, Month: month, Days: days})
+}
+
+type monthCell struct {
+	Day     int
+	Names   []string
+	Today   bool
+	LeapDay bool
+}
+
+type monthPage struct {
+	Country  string
+	Title    string
+	PrevLink string
+	NextLink string
+	Weekdays []string
+	Weeks    [][]*monthCell
+}
+
+// buildMonthGrid lays the days out in Monday-first weeks of the given year.
+// In common years 02-29 is kept as an extra cell after 02-28 so its names
+// are not lost.
+func buildMonthGrid(days []MonthDay, year int, month string, today string) [][]*monthCell {
+	first, _ := time.Parse("2006-01-02", fmt.Sprintf("%04d-%s-01", year, month))
+	offset := (int(first.Weekday()) + 6) % 7
+
+	cells := make([]*monthCell, offset)
+	for i, day := range days {
+		cells = append(cells, &monthCell{
+			Day:     i + 1,
+			Names:   day.Names,
+			Today:   day.Date == today,
+			LeapDay: day.Date == "02-29" && !isLeapYear(year),
+		})
+	}
+	for len(cells)%7 != 0 {
+		cells = append(cells, nil)
+	}
+
+	var weeks [][]*monthCell
+	for i := 0; i < len(cells); i += 7 {
+		weeks = append(weeks, cells[i:i+7])
+	}
+	return weeks
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// monthLink builds a link to another month, keeping the query so the
+// country and timezone stay selected
+func monthLink(month time.Month, query url.Values) string {
+	link := fmt.Sprintf("/month/%02d", int(month))
+	if encoded := query.Encode(); encoded != "" {
+		link += "?" + encoded
+	}
+	return link
+}
+
+type monthPageHandler struct {
+	db    *sql.DB
+	clock Clock
+}
+
+func NewMonthPageHandler(db *sql.DB) *monthPageHandler {
+	return &monthPageHandler{db: db, clock: systemClock{}}
+}
+
+func (h *monthPageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	matches := MonthPageRe.FindStringSubmatch(r.URL.Path)
+	if r.Method != http.MethodGet || len(matches) < 2 {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	now, err := requestNow(h.clock, r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	month, err := resolveMonth(matches[1], now)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	days, err := getNamedaysForMonth(h.db, country, month)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	first, _ := time.Parse("2006-01-02", fmt.Sprintf("%04d-%s-01", now.Year(), month))
+	query := r.URL.Query()
+	writePage(w, r, "month", monthPage{
+		Country:  country,
+		Title:    first.Format("January"),
+		PrevLink: monthLink(first.AddDate(0, -1, 0).Month(), query),
+		NextLink: monthLink(first.AddDate(0, 1, 0).Month(), query),
+		Weekdays: []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"},
+		Weeks:    buildMonthGrid(days, now.Year(), month, now.Format("01-02")),
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeMonthNamedays(t *testing.T, body []byte) MonthNamedays {
+	var result MonthNamedays
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	return result
+}
+
+func TestResolveMonth(t *testing.T) {
+	now := time.Date(2023, time.June, 24, 12, 0, 0, 0, time.UTC)
+	cases := map[string]string{"01": "01", "12": "12", "current": "06", "": "06"}
+	for input, expected := range cases {
+		result, err := resolveMonth(input, now)
+		if err != nil {
+			t.Errorf("resolveMonth(%q) returned an error: %v", input, err)
+		}
+		if result != expected {
+			t.Errorf("resolveMonth(%q) returned %s, expected %s", input, result, expected)
+		}
+	}
+
+	for _, input := range []string{"00", "13", "4", "april"} {
+		if _, err := resolveMonth(input, now); err == nil {
+			t.Errorf("resolveMonth(%q) expected an error", input)
+		}
+	}
+}
+
+func TestMonthHandler(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "02-29", "Leap")
+	insertTestNames(t, db, "02-01", "Brigita", "Indra")
+	insertTestNames(t, db, "03-01", "Ilgonis")
+	handler := NewMonthHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/month/02", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	result := decodeMonthNamedays(t, rr.Body.Bytes())
+	if result.Month != "02" || result.Country != "lv" {
+		t.Errorf("Unexpected month or country: %v", result)
+	}
+	if len(result.Days) != 29 {
+		t.Fatalf("Expected 29 days in February, got %d", len(result.Days))
+	}
+	if first := result.Days[0]; first.Date != "02-01" || len(first.Names) != 2 || first.Names[0] != "Brigita" {
+		t.Errorf("Unexpected first day: %v", first)
+	}
+	if result.Days[1].Names == nil || len(result.Days[1].Names) != 0 {
+		t.Errorf("Expected an empty name list for 02-02, got %v", result.Days[1].Names)
+	}
+	if last := result.Days[28]; last.Date != "02-29" || len(last.Names) != 1 {
+		t.Errorf("Unexpected last day: %v", last)
+	}
+}
+
+func TestMonthHandlerInvalidMonth(t *testing.T) {
+	_, db := createTestDb(t)
+	handler := NewMonthHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/month/13", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusBadRequest)
+
+	rr, req = setupTestRequest(t, http.MethodPost, "/api/v1/month/04", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusNotFound)
+}
+
+func TestBuildMonthGrid(t *testing.T) {
+	days := make([]MonthDay, 29)
+	for i := range days {
+		days[i] = MonthDay{Date: "02-" + time.Date(2000, 2, i+1, 0, 0, 0, 0, time.UTC).Format("02")}
+	}
+
+	// 1 February 2023 is a Wednesday, so two blank cells precede it
+	weeks := buildMonthGrid(days, 2023, "02", "02-14")
+	if weeks[0][0] != nil || weeks[0][1] != nil || weeks[0][2].Day != 1 {
+		t.Errorf("Expected the month to start on Wednesday, got %v", weeks[0])
+	}
+	if cell := weeks[2][1]; cell.Day != 14 || !cell.Today {
+		t.Errorf("Expected 02-14 to be highlighted, got %v", cell)
+	}
+	if cell := weeks[4][2]; cell.Day != 29 || !cell.LeapDay {
+		t.Errorf("Expected 02-29 to be flagged as a leap day, got %v", cell)
+	}
+
+	weeks = buildMonthGrid(days, 2024, "02", "")
+	for _, week := range weeks {
+		for _, cell := range week {
+			if cell != nil && cell.LeapDay {
+				t.Errorf("Did not expect a leap day flag in 2024, got %v", cell)
+			}
+		}
+	}
+}
+
+func TestMonthPageHandler(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "06-25", "Maija", "<script>")
+	handler := NewMonthPageHandler(db)
+	handler.clock = lateEveningUTC
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/month/?country=lv", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	body := rr.Body.String()
+	if !strings.Contains(body, "Namedays in June") {
+		t.Errorf("Expected the current month in Riga, got %s", body)
+	}
+	if !strings.Contains(body, `href="/month/05?country=lv"`) || !strings.Contains(body, `href="/month/07?country=lv"`) {
+		t.Errorf("Expected previous and next links keeping the country, got %s", body)
+	}
+	if !strings.Contains(body, `class="today"`) {
+		t.Errorf("Expected today to be highlighted, got %s", body)
+	}
+	if strings.Contains(body, "<script>") {
+		t.Errorf("Expected names to be escaped, got %s", body)
+	}
+}
+
+func TestMonthPageHandlerWrapsYear(t *testing.T) {
+	_, db := createTestDb(t)
+	handler := NewMonthPageHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/month/12", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	body := rr.Body.String()
+	if !strings.Contains(body, `href="/month/11"`) || !strings.Contains(body, `href="/month/01"`) {
+		t.Errorf("Expected December to link to November and January, got %s", body)
+	}
+}
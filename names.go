@@ -0,0 +1,155 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gosimple/slug"
+)
+
+var (
+	NameRe = regexp.MustCompile(`^/api/v1/names/([^/]+)$`)
+)
+
+// NameOccurrence is a single date on which a name is celebrated
+type NameOccurrence struct {
+	Name string `json:"name"`
+	Date string `json:"date"`
+}
+
+// NextOccurrence is the closest upcoming date for a name
+type NextOccurrence struct {
+	Date      string `json:"date"`
+	Year      int    `json:"year"`
+	DaysUntil int    `json:"days_until"`
+}
+
+// NameLookup is the JSON representation of a reverse name lookup
+type NameLookup struct {
+	Query       string           `json:"query"`
+	Country     string           `json:"country"`
+	Occurrences []NameOccurrence `json:"occurrences"`
+	Next        *NextOccurrence  `json:"next,omitempty"`
+}
+
+// findNameDates returns every date a name is celebrated on in a country's
+// calendar. Names are compared by slug, which makes the match case- and
+// diacritic-insensitive.
+func findNameDates(db *sql.DB, country, name string) ([]NameOccurrence, error) {
+	rows, err := db.Query("SELECT name, date FROM namedays WHERE country = ? AND slug = ? ORDER BY date, id", country, slug.Make(name))
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	occurrences := []NameOccurrence{}
+	for rows.Next() {
+		var o NameOccurrence
+		if err := rows.Scan(&o.Name, &o.Date); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		occurrences = append(occurrences, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return occurrences, nil
+}
+
+// nextOccurrence finds the first of the "MM-DD" dates on or after from.
+// 02-29 only occurs in leap years, so the search spans a full leap cycle.
+func nextOccurrence(dates []string, from time.Time) *NextOccurrence {
+	wanted := make(map[string]bool, len(dates))
+	for _, d := range dates {
+		wanted[d] = true
+	}
+
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= 4*366; i++ {
+		current := day.AddDate(0, 0, i)
+		if wanted[current.Format("01-02")] {
+			return &NextOccurrence{Date: current.Format("01-02"), Year: current.Year(), DaysUntil: i}
+		}
+	}
+	return nil
+}
+
+// parseReferenceDate accepts either "YYYY-MM-DD" or "MM-DD" (in the year of
+// now) and returns the corresponding day
+func parseReferenceDate(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return now, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+
+	monthDay, err := ParseMonthDay(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, _ := time.Parse("01-02", monthDay)
+	return time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+type nameHandler struct {
+	db    *sql.DB
+	clock Clock
+}
+
+func NewNameHandler(db *sql.DB) *nameHandler {
+	return &nameHandler{db: db, clock: systemClock{}}
+}
+
+func (h *nameHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	matches := NameRe.FindStringSubmatch(r.URL.Path)
+	if r.Method != http.MethodGet || len(matches) < 2 {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	now, err := requestNow(h.clock, r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	from, err := parseReferenceDate(r.URL.Query().Get("date"), now)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	occurrences, err := findNameDates(h.db, country, matches[1])
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+	if len(occurrences) == 0 {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	dates := make([]string, 0, len(occurrences))
+	for _, o := range occurrences {
+		dates = append(dates, o.Date)
+	}
+
+	writeJSON(w, r, http.StatusOK, NameLookup{
+		Query:       matches[1],
+		Country:     country,
+		Occurrences: occurrences,
+		Next:        nextOccurrence(dates, from),
+	})
+}
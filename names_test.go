@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestFindNameDatesIgnoresCaseAndDiacritics(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "01-02", "Īva")
+	insertTestNames(t, db, "05-19", "Iva")
+
+	for _, query := range []string{"Iva", "iva", "ĪVA", "īva"} {
+		occurrences, err := findNameDates(db, "lv", query)
+		if err != nil {
+			t.Fatalf("findNameDates(%q) returned an error: %v", query, err)
+		}
+		if len(occurrences) != 2 {
+			t.Errorf("findNameDates(%q) expected 2 occurrences, got %v", query, occurrences)
+		}
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	from := time.Date(2023, time.December, 30, 15, 0, 0, 0, time.UTC)
+
+	next := nextOccurrence([]string{"01-02", "06-24"}, from)
+	if next == nil || next.Date != "01-02" || next.Year != 2024 || next.DaysUntil != 3 {
+		t.Errorf("Unexpected next occurrence: %+v", next)
+	}
+
+	next = nextOccurrence([]string{"12-30"}, from)
+	if next == nil || next.DaysUntil != 0 {
+		t.Errorf("Expected occurrence today, got %+v", next)
+	}
+
+	// 02-29 only comes around in leap years
+	next = nextOccurrence([]string{"02-29"}, time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC))
+	if next == nil || next.Year != 2028 {
+		t.Errorf("Expected next 02-29 in 2028, got %+v", next)
+	}
+}
+
+func TestNameHandler(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "06-24", "Jānis")
+	handler := NewNameHandler(db)
+
+	path := "/api/v1/names/" + url.PathEscape("Jānis") + "?date=06-20"
+	rr, req := setupTestRequest(t, http.MethodGet, path, nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+
+	var result NameLookup
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(result.Occurrences) != 1 || result.Occurrences[0].Name != "Jānis" || result.Occurrences[0].Date != "06-24" {
+		t.Errorf("Unexpected occurrences: %v", result.Occurrences)
+	}
+	if result.Next == nil || result.Next.DaysUntil != 4 {
+		t.Errorf("Expected next occurrence in 4 days, got %+v", result.Next)
+	}
+}
+
+func TestNameHandlerNotFound(t *testing.T) {
+	_, db := createTestDb(t)
+	handler := NewNameHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/names/nobody", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusNotFound)
+}
+
+func TestNameHandlerInvalidReferenceDate(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "06-24", "Jānis")
+	handler := NewNameHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/names/Janis?date=13-40", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusBadRequest)
+}
@@ -0,0 +1,87 @@
+// Package contacts reads lists of people from vCard and CSV files so their
+// first names can be matched against the nameday calendar.
+package contacts
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/zrks/namedays/pkg/tabular"
+)
+
+var (
+	csvColumns = []string{"name", "first_name", "last_name", "email"}
+)
+
+// Contact is a single person of a contact list
+type Contact struct {
+	Line      int    `json:"line,omitempty"`
+	Name      string `json:"name"`
+	FirstName string `json:"first_name"`
+	Email     string `json:"email,omitempty"`
+}
+
+// FirstName returns the first word of a full name
+func FirstName(name string) string {
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// csvFormat is the layout of a contact list separated by comma
+func csvFormat(comma rune) tabular.Format {
+	return tabular.Format{
+		Comma:    comma,
+		Columns:  csvColumns,
+		Defaults: tabular.Columns{"name": 0, "email": 1},
+		IsHeader: func(columns tabular.Columns) bool {
+			_, hasName := columns["name"]
+			_, hasFirstName := columns["first_name"]
+			return hasName || hasFirstName
+		},
+	}
+}
+
+// ReadCSV parses a CSV (comma ',') or TSV (comma '\t') contact list. A
+// header row may name the columns name, first_name, last_name and email in
+// any order; without one the columns are name[,email]. A leading UTF-8 BOM
+// is skipped and every invalid row is reported in the returned
+// tabular.RowErrors.
+func ReadCSV(r io.Reader, comma rune) ([]Contact, error) {
+	var contacts []Contact
+	err := tabular.Read(r, csvFormat(comma), func(row tabular.Row) error {
+		contact, err := parseRow(row)
+		if err != nil {
+			return err
+		}
+		contacts = append(contacts, contact)
+		return nil
+	})
+	if _, ok := err.(tabular.RowErrors); err != nil && !ok {
+		return nil, fmt.Errorf("error reading contacts: %w", err)
+	}
+	return contacts, err
+}
+
+func parseRow(row tabular.Row) (Contact, error) {
+	contact := Contact{
+		Line:      row.Line,
+		Name:      row.Field("name"),
+		FirstName: row.Field("first_name"),
+		Email:     row.Field("email"),
+	}
+	if contact.Name == "" {
+		contact.Name = strings.TrimSpace(contact.FirstName + " " + row.Field("last_name"))
+	}
+	if contact.FirstName == "" {
+		contact.FirstName = FirstName(contact.Name)
+	}
+
+	if contact.FirstName == "" {
+		return Contact{}, fmt.Errorf("empty name")
+	}
+	return contact, nil
+}
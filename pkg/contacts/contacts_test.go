@@ -0,0 +1,77 @@
+package contacts
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zrks/namedays/pkg/tabular"
+)
+
+func TestReadCSVWithHeader(t *testing.T) {
+	input := "\ufeffEmail,First Name,Last Name\njanis@example.com,Jānis,Bērziņš\n,Anna,\n"
+	contacts, err := ReadCSV(strings.NewReader(input), ',')
+	if err != nil {
+		t.Fatalf("ReadCSV returned an error: %v", err)
+	}
+
+	expected := []Contact{
+		{Line: 2, Name: "Jānis Bērziņš", FirstName: "Jānis", Email: "janis@example.com"},
+		{Line: 3, Name: "Anna", FirstName: "Anna"},
+	}
+	if len(contacts) != len(expected) {
+		t.Fatalf("Expected %d contacts, got %v", len(expected), contacts)
+	}
+	for i := range expected {
+		if contacts[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], contacts[i])
+		}
+	}
+}
+
+func TestReadCSVWithoutHeader(t *testing.T) {
+	input := "Līga Ozola\tliga@example.com\nMārtiņš\n"
+	contacts, err := ReadCSV(strings.NewReader(input), '\t')
+	if err != nil {
+		t.Fatalf("ReadCSV returned an error: %v", err)
+	}
+	if len(contacts) != 2 {
+		t.Fatalf("Expected 2 contacts, got %v", contacts)
+	}
+	if contacts[0].FirstName != "Līga" || contacts[0].Email != "liga@example.com" {
+		t.Errorf("Unexpected first contact: %v", contacts[0])
+	}
+	if contacts[1].Name != "Mārtiņš" || contacts[1].FirstName != "Mārtiņš" {
+		t.Errorf("Unexpected second contact: %v", contacts[1])
+	}
+}
+
+func TestReadCSVReportsInvalidRows(t *testing.T) {
+	input := "name,email\nAnna,anna@example.com\n ,nobody@example.com\n"
+	contacts, err := ReadCSV(strings.NewReader(input), ',')
+
+	var rowErrors tabular.RowErrors
+	if !errors.As(err, &rowErrors) {
+		t.Fatalf("Expected RowErrors, got %v", err)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Line != 3 {
+		t.Errorf("Expected an error on line 3, got %v", rowErrors)
+	}
+	if len(contacts) != 1 {
+		t.Errorf("Expected the valid row to be kept, got %v", contacts)
+	}
+}
+
+func TestFirstName(t *testing.T) {
+	cases := map[string]string{
+		"Jānis Bērziņš": "Jānis",
+		"  Anna ":       "Anna",
+		"Anna-Marija K": "Anna-Marija",
+		"":              "",
+	}
+	for input, expected := range cases {
+		if result := FirstName(input); result != expected {
+			t.Errorf("FirstName(%q) returned %q, expected %q", input, result, expected)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package contacts
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+
+	"github.com/zrks/namedays/pkg/tabular"
+)
+
+// vcardProperty is a single unfolded content line of a vCard
+type vcardProperty struct {
+	Line   int
+	Name   string
+	Params []string
+	Value  string
+}
+
+// ReadVCard parses every card of a vCard 2.1, 3.0 or 4.0 file. The first
+// name comes from the given name of the N property, falling back to the
+// first word of FN. Cards without a name are reported in the returned
+// tabular.RowErrors.
+func ReadVCard(r io.Reader) ([]Contact, error) {
+	properties, err := readVCardProperties(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var contacts []Contact
+	var rowErrors tabular.RowErrors
+	var card *Contact
+	var given string
+	for _, p := range properties {
+		switch p.Name {
+		case "BEGIN":
+			if !strings.EqualFold(p.Value, "VCARD") {
+				continue
+			}
+			if card != nil {
+				rowErrors = append(rowErrors, tabular.RowError{Line: card.Line, Message: "card is missing END:VCARD"})
+			}
+			card, given = &Contact{Line: p.Line}, ""
+		case "END":
+			if !strings.EqualFold(p.Value, "VCARD") {
+				continue
+			}
+			if card == nil {
+				rowErrors = append(rowErrors, tabular.RowError{Line: p.Line, Message: "END:VCARD without BEGIN:VCARD"})
+				continue
+			}
+			if given != "" {
+				card.FirstName = FirstName(given)
+			} else {
+				card.FirstName = FirstName(card.Name)
+			}
+			if card.Name == "" {
+				card.Name = card.FirstName
+			}
+			if card.FirstName == "" {
+				rowErrors = append(rowErrors, tabular.RowError{Line: card.Line, Message: "card has no name"})
+			} else {
+				contacts = append(contacts, *card)
+			}
+			card = nil
+		case "FN":
+			if card != nil {
+				card.Name = unescapeVCard(p.Value)
+			}
+		case "N":
+			if card != nil {
+				if parts := splitVCard(p.Value, ';'); len(parts) > 1 {
+					given = unescapeVCard(splitVCard(parts[1], ',')[0])
+				}
+			}
+		case "EMAIL":
+			if card != nil && card.Email == "" {
+				card.Email = unescapeVCard(p.Value)
+			}
+		}
+	}
+	if card != nil {
+		rowErrors = append(rowErrors, tabular.RowError{Line: card.Line, Message: "card is missing END:VCARD"})
+	}
+
+	if len(rowErrors) > 0 {
+		return contacts, rowErrors
+	}
+	return contacts, nil
+}
+
+// readVCardProperties unfolds the content lines of a vCard file and splits
+// them into name, parameters and value. Quoted-printable values, which
+// vCard 2.1 exports use for non-ASCII names, are decoded.
+func readVCardProperties(r io.Reader) ([]vcardProperty, error) {
+	scanner := bufio.NewScanner(r)
+	var properties []vcardProperty
+	var current *vcardProperty
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		// Folded lines continue the previous one after a single space or tab,
+		// quoted-printable soft line breaks end in "="
+		if current != nil && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			current.Value += line[1:]
+			continue
+		}
+		if current != nil && isQuotedPrintable(current.Params) && strings.HasSuffix(current.Value, "=") {
+			current.Value = current.Value[:len(current.Value)-1] + line
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			current = nil
+			continue
+		}
+		params := strings.Split(line[:colon], ";")
+		name := strings.ToUpper(params[0])
+		if dot := strings.LastIndex(name, "."); dot >= 0 {
+			name = name[dot+1:]
+		}
+		properties = append(properties, vcardProperty{Line: lineNo, Name: name, Params: params[1:], Value: line[colon+1:]})
+		current = &properties[len(properties)-1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading contacts: %w", err)
+	}
+
+	for i := range properties {
+		if isQuotedPrintable(properties[i].Params) {
+			decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(properties[i].Value)))
+			if err != nil {
+				return nil, tabular.RowErrors{{Line: properties[i].Line, Message: fmt.Sprintf("invalid quoted-printable value: %v", err)}}
+			}
+			properties[i].Value = string(decoded)
+		}
+	}
+	return properties, nil
+}
+
+func isQuotedPrintable(params []string) bool {
+	for _, param := range params {
+		param = strings.ToUpper(param)
+		if param == "QUOTED-PRINTABLE" || param == "ENCODING=QUOTED-PRINTABLE" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitVCard splits a structured value on sep, ignoring escaped separators.
+// The parts are left escaped so they can be split further.
+func splitVCard(value string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' {
+			i++
+			continue
+		}
+		if value[i] == sep {
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+func unescapeVCard(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+			if value[i] == 'n' || value[i] == 'N' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(value[i])
+			}
+			continue
+		}
+		b.WriteByte(value[i])
+	}
+	return strings.TrimSpace(b.String())
+}
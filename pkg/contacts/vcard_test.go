@@ -0,0 +1,87 @@
+package contacts
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zrks/namedays/pkg/tabular"
+)
+
+func TestReadVCard(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"N:Bērziņš;Jānis;;;",
+		"FN:Jānis Bērziņš",
+		"item1.EMAIL;TYPE=INTERNET:janis@example.com",
+		"END:VCARD",
+		"BEGIN:VCARD",
+		"VERSION:4.0",
+		"FN:Anna Kalniņa\\, PhD",
+		"EMAIL:anna@exa",
+		" mple.com",
+		"END:VCARD",
+		"",
+	}, "\r\n")
+
+	contacts, err := ReadVCard(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadVCard returned an error: %v", err)
+	}
+
+	expected := []Contact{
+		{Line: 1, Name: "Jānis Bērziņš", FirstName: "Jānis", Email: "janis@example.com"},
+		{Line: 7, Name: "Anna Kalniņa, PhD", FirstName: "Anna", Email: "anna@example.com"},
+	}
+	if len(contacts) != len(expected) {
+		t.Fatalf("Expected %d contacts, got %v", len(expected), contacts)
+	}
+	for i := range expected {
+		if contacts[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], contacts[i])
+		}
+	}
+}
+
+func TestReadVCardQuotedPrintable(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCARD",
+		"VERSION:2.1",
+		"N;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:Ozola;L=C4=AB=",
+		"ga",
+		"END:VCARD",
+	}, "\n")
+
+	contacts, err := ReadVCard(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadVCard returned an error: %v", err)
+	}
+	if len(contacts) != 1 || contacts[0].FirstName != "Līga" || contacts[0].Name != "Līga" {
+		t.Errorf("Expected a single contact named Līga, got %v", contacts)
+	}
+}
+
+func TestReadVCardReportsInvalidCards(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCARD",
+		"EMAIL:nobody@example.com",
+		"END:VCARD",
+		"END:VCARD",
+		"BEGIN:VCARD",
+		"FN:Anna",
+	}, "\n")
+
+	contacts, err := ReadVCard(strings.NewReader(input))
+
+	var rowErrors tabular.RowErrors
+	if !errors.As(err, &rowErrors) {
+		t.Fatalf("Expected RowErrors, got %v", err)
+	}
+	if len(rowErrors) != 3 || rowErrors[0].Line != 1 || rowErrors[1].Line != 4 || rowErrors[2].Line != 5 {
+		t.Errorf("Unexpected errors: %v", rowErrors)
+	}
+	if len(contacts) != 0 {
+		t.Errorf("Expected no contacts, got %v", contacts)
+	}
+}
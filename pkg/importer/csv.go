@@ -0,0 +1,224 @@
+package importer
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zrks/namedays/pkg/tabular"
+)
+
+// Supported gender values. An empty gender means unknown.
+const (
+	GenderFemale = "f"
+	GenderMale   = "m"
+)
+
+var (
+	countryCodeRe = regexp.MustCompile(`^[a-z]{2}$`)
+	csvColumns    = []string{"date", "name", "country", "gender"}
+	genderAliases = map[string]string{
+		"":       "",
+		"f":      GenderFemale,
+		"female": GenderFemale,
+		"m":      GenderMale,
+		"male":   GenderMale,
+	}
+)
+
+// Record is a single nameday row of a tabular dataset
+type Record struct {
+	Line    int    `json:"line,omitempty"`
+	Date    string `json:"date"`
+	Name    string `json:"name"`
+	Country string `json:"country,omitempty"`
+	Gender  string `json:"gender,omitempty"`
+}
+
+// csvFormat is the layout of a tabular dataset separated by comma
+func csvFormat(comma rune) tabular.Format {
+	return tabular.Format{
+		Comma:    comma,
+		Columns:  csvColumns,
+		Defaults: tabular.Columns{"date": 0, "name": 1, "country": 2, "gender": 3},
+		IsHeader: func(columns tabular.Columns) bool {
+			_, hasDate := columns["date"]
+			_, hasName := columns["name"]
+			return hasDate && hasName
+		},
+	}
+}
+
+// ReadCSV parses a CSV (comma ',') or TSV (comma '\t') dataset with the
+// columns date,name[,country,gender]. A leading UTF-8 BOM is skipped and a
+// header row, if present, may list the columns in any order. Every invalid
+// row is reported in the returned tabular.RowErrors.
+func ReadCSV(r io.Reader, comma rune) ([]Record, error) {
+	var records []Record
+	err := tabular.Read(r, csvFormat(comma), func(row tabular.Row) error {
+		record, err := parseRecord(row)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	if _, ok := err.(tabular.RowErrors); err != nil && !ok {
+		return nil, fmt.Errorf("error reading dataset: %w", err)
+	}
+	return records, err
+}
+
+func parseRecord(row tabular.Row) (Record, error) {
+	if len(row.Fields) <= row.Columns["name"] || len(row.Fields) <= row.Columns["date"] {
+		return Record{}, fmt.Errorf("expected at least %d fields, got %d", maxInt(row.Columns["date"], row.Columns["name"])+1, len(row.Fields))
+	}
+
+	record := Record{
+		Line:    row.Line,
+		Date:    row.Field("date"),
+		Name:    row.Field("name"),
+		Country: strings.ToLower(row.Field("country")),
+	}
+
+	if !ValidDate(record.Date) {
+		return Record{}, fmt.Errorf("invalid date %q", record.Date)
+	}
+	if record.Name == "" {
+		return Record{}, fmt.Errorf("empty name")
+	}
+	if record.Country != "" && !countryCodeRe.MatchString(record.Country) {
+		return Record{}, fmt.Errorf("invalid country %q", record.Country)
+	}
+
+	gender, ok := genderAliases[strings.ToLower(row.Field("gender"))]
+	if !ok {
+		return Record{}, fmt.Errorf("invalid gender %q", row.Field("gender"))
+	}
+	record.Gender = gender
+
+	return record, nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// WriteCSV writes records with a header row, using comma as the separator
+func WriteCSV(w io.Writer, records []Record, comma rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := writer.Write([]string{r.Date, r.Name, r.Country, r.Gender}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ImportRecords applies tabular records through Import, one country at a
+// time. Records without a country belong to opts.Country. Known genders
+// are stored once the names are in place.
+func ImportRecords(db *sql.DB, records []Record, opts Options) ([]*Report, error) {
+	byCountry := make(map[string]Namedays)
+	for _, r := range records {
+		country := r.Country
+		if country == "" {
+			country = opts.Country
+		}
+		if byCountry[country] == nil {
+			byCountry[country] = make(Namedays)
+		}
+		byCountry[country][r.Date] = append(byCountry[country][r.Date], r.Name)
+	}
+
+	countries := make([]string, 0, len(byCountry))
+	for country := range byCountry {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+
+	var reports []*Report
+	for _, country := range countries {
+		countryOpts := opts
+		countryOpts.Country = country
+		report, err := Import(db, byCountry[country], countryOpts)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	if opts.DryRun {
+		return reports, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, r := range records {
+		if r.Gender == "" {
+			continue
+		}
+		country := r.Country
+		if country == "" {
+			country = opts.Country
+		}
+		if _, err = tx.Exec("UPDATE namedays SET gender = ? WHERE country = ? AND date = ? AND name = ?", r.Gender, country, r.Date, r.Name); err != nil {
+			return nil, fmt.Errorf("failed to set gender: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit genders: %w", err)
+	}
+	return reports, nil
+}
+
+// ExportRecords returns every stored nameday ordered by country and date.
+// An empty country exports all countries.
+func ExportRecords(db *sql.DB, country string) ([]Record, error) {
+	query := "SELECT date, name, country, gender FROM namedays"
+	var args []interface{}
+	if country != "" {
+		query += " WHERE country = ?"
+		args = append(args, country)
+	}
+	query += " ORDER BY country, date, id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	records := []Record{}
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.Date, &r.Name, &r.Country, &r.Gender); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		records = append(records, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return records, nil
+}
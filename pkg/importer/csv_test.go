@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zrks/namedays/pkg/tabular"
+)
+
+func TestReadCSVWithHeaderAndBOM(t *testing.T) {
+	input := "\ufeffname,date,gender\nJānis,06-24,male\n\"Anna\",07-26,F\n"
+
+	records, err := ReadCSV(strings.NewReader(input), ',')
+	if err != nil {
+		t.Fatalf("ReadCSV returned an error: %v", err)
+	}
+
+	expected := []Record{
+		{Line: 2, Date: "06-24", Name: "Jānis", Gender: GenderMale},
+		{Line: 3, Date: "07-26", Name: "Anna", Gender: GenderFemale},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Unexpected records: got %+v want %+v", records, expected)
+	}
+}
+
+func TestReadTSVWithoutHeader(t *testing.T) {
+	input := "06-24\tJānis\tlv\n06-24\tJonas\tLT\tm\n\n"
+
+	records, err := ReadCSV(strings.NewReader(input), '\t')
+	if err != nil {
+		t.Fatalf("ReadCSV returned an error: %v", err)
+	}
+	if len(records) != 2 || records[1].Country != "lt" || records[1].Gender != GenderMale {
+		t.Errorf("Unexpected records: %+v", records)
+	}
+}
+
+func TestReadCSVRowErrors(t *testing.T) {
+	input := "date,name,country,gender\n" +
+		"06-24,Jānis\n" +
+		"13-40,Nobody\n" +
+		"06-24,\n" +
+		"06-24,Anna,latvia\n" +
+		"06-24,Anna,lv,x\n" +
+		"06-24\n"
+
+	records, err := ReadCSV(strings.NewReader(input), ',')
+
+	var rowErrors tabular.RowErrors
+	if !errors.As(err, &rowErrors) {
+		t.Fatalf("Expected RowErrors, got %v", err)
+	}
+	lines := []int{}
+	for _, e := range rowErrors {
+		lines = append(lines, e.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{3, 4, 5, 6, 7}) {
+		t.Errorf("Unexpected error lines: %v (%v)", lines, err)
+	}
+	if len(records) != 1 || records[0].Name != "Jānis" {
+		t.Errorf("Expected the valid row to be returned, got %+v", records)
+	}
+	if !strings.Contains(err.Error(), `line 3: invalid date "13-40"`) {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
+
+func TestImportRecordsAndExport(t *testing.T) {
+	db := openTestDb(t)
+	records := []Record{
+		{Date: "06-24", Name: "Jānis", Gender: GenderMale},
+		{Date: "06-24", Name: "Jonas", Country: "lt"},
+		{Date: "07-26", Name: "Anna", Gender: GenderFemale},
+	}
+
+	reports, err := ImportRecords(db, records, Options{Country: "lv"})
+	if err != nil {
+		t.Fatalf("ImportRecords returned an error: %v", err)
+	}
+	if len(reports) != 2 || reports[0].Country != "lt" || reports[1].Added != 2 {
+		t.Errorf("Unexpected reports: %+v", reports)
+	}
+
+	exported, err := ExportRecords(db, "lv")
+	if err != nil {
+		t.Fatalf("ExportRecords returned an error: %v", err)
+	}
+	expected := []Record{
+		{Date: "06-24", Name: "Jānis", Country: "lv", Gender: GenderMale},
+		{Date: "07-26", Name: "Anna", Country: "lv", Gender: GenderFemale},
+	}
+	if !reflect.DeepEqual(exported, expected) {
+		t.Errorf("Unexpected export: got %+v want %+v", exported, expected)
+	}
+
+	all, _ := ExportRecords(db, "")
+	if len(all) != 3 {
+		t.Errorf("Expected 3 records across countries, got %d", len(all))
+	}
+}
+
+func TestWriteCSVRoundTrip(t *testing.T) {
+	records := []Record{
+		{Date: "03-24", Name: "Kazmirina, Sidars", Country: "lv"},
+		{Date: "06-24", Name: "Jānis", Country: "lv", Gender: GenderMale},
+	}
+
+	for _, comma := range []rune{',', '\t'} {
+		var buf bytes.Buffer
+		if err := WriteCSV(&buf, records, comma); err != nil {
+			t.Fatalf("WriteCSV returned an error: %v", err)
+		}
+
+		read, err := ReadCSV(&buf, comma)
+		if err != nil {
+			t.Fatalf("ReadCSV returned an error: %v", err)
+		}
+		for i := range read {
+			read[i].Line = 0
+		}
+		if !reflect.DeepEqual(read, records) {
+			t.Errorf("Round trip with %q changed records: got %+v want %+v", comma, read, records)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultFetchTimeout = 30 * time.Second
+	DefaultMaxBytes     = 5 << 20
+)
+
+var (
+	ChecksumMismatchErr = errors.New("dataset checksum mismatch")
+	TooLargeErr         = errors.New("dataset exceeds size limit")
+)
+
+// FetchState holds the cache validators of the last successful fetch, so
+// unchanged datasets are not downloaded and applied again
+type FetchState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	SHA256       string `json:"sha256,omitempty"`
+}
+
+// LoadFetchState reads a state file, returning an empty state if it does
+// not exist yet
+func LoadFetchState(path string) (FetchState, error) {
+	var state FetchState
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read fetch state: %w", err)
+	}
+	if err = json.Unmarshal(content, &state); err != nil {
+		return state, fmt.Errorf("failed to parse fetch state: %w", err)
+	}
+	return state, nil
+}
+
+// Save writes the state to path
+func (s FetchState) Save(path string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fetch state: %w", err)
+	}
+	if err = os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write fetch state: %w", err)
+	}
+	return nil
+}
+
+// Fetcher downloads JSON datasets over HTTP
+type Fetcher struct {
+	Client *http.Client
+	// MaxBytes caps the size of the response body
+	MaxBytes int64
+	// SHA256 is the expected hex checksum of the body, if known
+	SHA256 string
+	// State carries cache validators between fetches of the same URL
+	State FetchState
+}
+
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		Client:   &http.Client{Timeout: DefaultFetchTimeout},
+		MaxBytes: DefaultMaxBytes,
+	}
+}
+
+// Fetch downloads the dataset at url. It returns nil data, and no error,
+// when the server reports that the dataset has not changed since the last
+// fetch or when the body is identical to it.
+func (f *Fetcher) Fetch(ctx context.Context, url string) (Namedays, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	if f.State.URL == url {
+		if f.State.ETag != "" {
+			req.Header.Set("If-None-Match", f.State.ETag)
+		}
+		if f.State.LastModified != "" {
+			req.Header.Set("If-Modified-Since", f.State.LastModified)
+		}
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received non-OK HTTP status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if int64(len(body)) > f.MaxBytes {
+		return nil, fmt.Errorf("%w of %d bytes", TooLargeErr, f.MaxBytes)
+	}
+
+	sum := sha256.Sum256(body)
+	checksum := hex.EncodeToString(sum[:])
+	if f.SHA256 != "" && !strings.EqualFold(f.SHA256, checksum) {
+		return nil, fmt.Errorf("%w: got %s want %s", ChecksumMismatchErr, checksum, f.SHA256)
+	}
+
+	state := FetchState{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		SHA256:       checksum,
+	}
+	if f.State.URL == url && f.State.SHA256 == checksum {
+		f.State = state
+		return nil, nil
+	}
+
+	var data Namedays
+	if err = json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+	for date := range data {
+		if !ValidDate(date) {
+			return nil, fmt.Errorf("dataset contains invalid date %q", date)
+		}
+	}
+
+	f.State = state
+	return data, nil
+}
@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDatasetJSON = `{"06-24":["Jānis"],"07-26":["Anna"]}`
+
+func testChecksum(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// newCachingServer serves body with an ETag and honours If-None-Match
+func newCachingServer(t *testing.T, body *string, requests *int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		etag := `"` + testChecksum(*body) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+		w.Write([]byte(*body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetch(t *testing.T) {
+	body, requests := testDatasetJSON, 0
+	server := newCachingServer(t, &body, &requests)
+	fetcher := NewFetcher()
+
+	data, err := fetcher.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch returned an error: %v", err)
+	}
+	if len(data) != 2 || data["06-24"][0] != "Jānis" {
+		t.Errorf("Unexpected data: %v", data)
+	}
+	if fetcher.State.ETag == "" || fetcher.State.SHA256 != testChecksum(body) {
+		t.Errorf("Expected cache validators to be recorded, got %+v", fetcher.State)
+	}
+
+	// The second request is answered with 304 Not Modified
+	data, err = fetcher.Fetch(context.Background(), server.URL)
+	if err != nil || data != nil {
+		t.Errorf("Expected no data for an unchanged dataset, got %v (%v)", data, err)
+	}
+
+	body = `{"06-24":["Jānis","Jāņa"]}`
+	data, err = fetcher.Fetch(context.Background(), server.URL)
+	if err != nil || len(data["06-24"]) != 2 {
+		t.Errorf("Expected the changed dataset, got %v (%v)", data, err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+}
+
+func TestFetchIfModifiedSince(t *testing.T) {
+	lastModified := "Mon, 01 Jan 2024 00:00:00 GMT"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(testDatasetJSON))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher()
+	if data, err := fetcher.Fetch(context.Background(), server.URL); err != nil || data == nil {
+		t.Fatalf("Expected data on the first fetch, got %v (%v)", data, err)
+	}
+	if data, err := fetcher.Fetch(context.Background(), server.URL); err != nil || data != nil {
+		t.Errorf("Expected no data on the second fetch, got %v (%v)", data, err)
+	}
+}
+
+func TestFetchChecksum(t *testing.T) {
+	body, requests := testDatasetJSON, 0
+	server := newCachingServer(t, &body, &requests)
+
+	fetcher := NewFetcher()
+	fetcher.SHA256 = strings.ToUpper(testChecksum(body))
+	if _, err := fetcher.Fetch(context.Background(), server.URL); err != nil {
+		t.Errorf("Expected a matching checksum, got %v", err)
+	}
+
+	fetcher = NewFetcher()
+	fetcher.SHA256 = testChecksum("something else")
+	if _, err := fetcher.Fetch(context.Background(), server.URL); !errors.Is(err, ChecksumMismatchErr) {
+		t.Errorf("Expected ChecksumMismatchErr, got %v", err)
+	}
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	body, requests := testDatasetJSON, 0
+	server := newCachingServer(t, &body, &requests)
+
+	fetcher := NewFetcher()
+	fetcher.MaxBytes = 10
+	if _, err := fetcher.Fetch(context.Background(), server.URL); !errors.Is(err, TooLargeErr) {
+		t.Errorf("Expected TooLargeErr, got %v", err)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(testDatasetJSON))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher()
+	fetcher.Client.Timeout = 20 * time.Millisecond
+	if _, err := fetcher.Fetch(context.Background(), server.URL); err == nil {
+		t.Error("Expected a timeout error")
+	}
+}
+
+func TestFetchRejectsBadResponses(t *testing.T) {
+	responses := map[string]func(w http.ResponseWriter){
+		"status":   func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+		"json":     func(w http.ResponseWriter) { w.Write([]byte("not json")) },
+		"date key": func(w http.ResponseWriter) { w.Write([]byte(`{"13-40":["Nobody"]}`)) },
+	}
+	for name, respond := range responses {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { respond(w) }))
+
+		fetcher := NewFetcher()
+		if _, err := fetcher.Fetch(context.Background(), server.URL); err == nil {
+			t.Errorf("Expected an error for a bad %s", name)
+		}
+		if fetcher.State.SHA256 != "" {
+			t.Errorf("Expected no cache validators after a bad %s", name)
+		}
+		server.Close()
+	}
+}
+
+func TestFetchStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := LoadFetchState(path)
+	if err != nil || state != (FetchState{}) {
+		t.Fatalf("Expected an empty state for a missing file, got %+v (%v)", state, err)
+	}
+
+	saved := FetchState{URL: "http://example.com/lv.json", ETag: `"abc"`, SHA256: "123"}
+	if err = saved.Save(path); err != nil {
+		t.Fatalf("Save returned an error: %v", err)
+	}
+	if state, err = LoadFetchState(path); err != nil || state != saved {
+		t.Errorf("Expected %+v, got %+v (%v)", saved, state, err)
+	}
+}
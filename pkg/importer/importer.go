@@ -0,0 +1,203 @@
+// Package importer loads nameday datasets into the namedays table. Imports
+// are idempotent: running the same dataset twice leaves the table unchanged.
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/gosimple/slug"
+)
+
+// Namedays maps "MM-DD" dates to the names celebrated on them
+type Namedays map[string][]string
+
+// Options controls how a dataset is applied
+type Options struct {
+	// Country is the calendar the dataset belongs to
+	Country string
+	// Prune deletes names of the country that are no longer in the dataset.
+	// Names added or edited through the API are never pruned.
+	Prune bool
+	// DryRun computes the report without changing the database
+	DryRun bool
+}
+
+// DateDiff lists the changes an import makes on a single date
+type DateDiff struct {
+	Date    string   `json:"date"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Report summarises an import. Removed counts imported names that are in
+// the database but not in the dataset; they are only deleted when Pruned.
+type Report struct {
+	Country   string     `json:"country"`
+	Added     int        `json:"added"`
+	Removed   int        `json:"removed"`
+	Unchanged int        `json:"unchanged"`
+	DryRun    bool       `json:"dry_run"`
+	Pruned    bool       `json:"pruned"`
+	Dates     []DateDiff `json:"dates"`
+}
+
+// Import upserts data into the namedays table and reports the difference
+// between the dataset and what was stored before
+func Import(db *sql.DB, data Namedays, opts Options) (*Report, error) {
+	if opts.Country == "" {
+		return nil, fmt.Errorf("import requires a country")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	existing, err := loadExisting(tx, opts.Country)
+	if err != nil {
+		return nil, err
+	}
+
+	report := diff(existing, data)
+	report.Country = opts.Country
+	report.DryRun = opts.DryRun
+	report.Pruned = opts.Prune
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	insert, err := tx.Prepare(`INSERT INTO namedays (date, name, slug, country) VALUES (?, ?, ?, ?)
+		ON CONFLICT (country, date, name) DO UPDATE SET slug = excluded.slug, source = 'dataset'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer insert.Close()
+
+	remove, err := tx.Prepare("DELETE FROM namedays WHERE country = ? AND date = ? AND name = ? AND source = 'dataset'")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer remove.Close()
+
+	for _, d := range report.Dates {
+		for _, name := range d.Added {
+			if _, err = insert.Exec(d.Date, name, slug.Make(name), opts.Country); err != nil {
+				return nil, fmt.Errorf("failed to insert nameday: %w", err)
+			}
+		}
+		if !opts.Prune {
+			continue
+		}
+		for _, name := range d.Removed {
+			if _, err = remove.Exec(opts.Country, d.Date, name); err != nil {
+				return nil, fmt.Errorf("failed to remove nameday: %w", err)
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return report, nil
+}
+
+// loadExisting returns the imported names of a country by date. Names
+// from the API are left out, so a dataset that also has them adopts them.
+func loadExisting(tx *sql.Tx, country string) (map[string]map[string]bool, error) {
+	rows, err := tx.Query("SELECT date, name FROM namedays WHERE country = ? AND source = 'dataset'", country)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]map[string]bool)
+	for rows.Next() {
+		var date, name string
+		if err := rows.Scan(&date, &name); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		if existing[date] == nil {
+			existing[date] = make(map[string]bool)
+		}
+		existing[date][name] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return existing, nil
+}
+
+// diff compares the stored names with the dataset, date by date
+func diff(existing map[string]map[string]bool, data Namedays) *Report {
+	dates := make(map[string]bool)
+	for date := range existing {
+		dates[date] = true
+	}
+	for date := range data {
+		dates[date] = true
+	}
+
+	sorted := make([]string, 0, len(dates))
+	for date := range dates {
+		sorted = append(sorted, date)
+	}
+	sort.Strings(sorted)
+
+	report := &Report{Dates: []DateDiff{}}
+	for _, date := range sorted {
+		d := DateDiff{Date: date}
+		wanted := make(map[string]bool)
+		for _, name := range data[date] {
+			if wanted[name] {
+				continue
+			}
+			wanted[name] = true
+			if existing[date][name] {
+				report.Unchanged++
+			} else {
+				d.Added = append(d.Added, name)
+			}
+		}
+		for name := range existing[date] {
+			if !wanted[name] {
+				d.Removed = append(d.Removed, name)
+			}
+		}
+		sort.Strings(d.Removed)
+
+		report.Added += len(d.Added)
+		report.Removed += len(d.Removed)
+		if len(d.Added) > 0 || len(d.Removed) > 0 {
+			report.Dates = append(report.Dates, d)
+		}
+	}
+	return report
+}
+
+// Print writes a human readable summary followed by the per-date diff
+func (r *Report) Print(w io.Writer) {
+	removed := "removed"
+	if !r.Pruned {
+		removed = "not in source (kept)"
+	}
+	mode := ""
+	if r.DryRun {
+		mode = " (dry run)"
+	}
+
+	fmt.Fprintf(w, "%s: %d added, %d %s, %d unchanged%s\n", r.Country, r.Added, r.Removed, removed, r.Unchanged, mode)
+	for _, d := range r.Dates {
+		for _, name := range d.Added {
+			fmt.Fprintf(w, "%s\t+ %s\n", d.Date, name)
+		}
+		for _, name := range d.Removed {
+			fmt.Fprintf(w, "%s\t- %s\n", d.Date, name)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"bytes"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/zrks/namedays/pkg/migrations"
+)
+
+func openTestDb(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "namedays.db"))
+	if err != nil {
+		t.Fatal("Failed to open database:", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err = migrations.Up(db); err != nil {
+		t.Fatal("Failed to migrate database:", err)
+	}
+	return db
+}
+
+func countRows(t *testing.T, db *sql.DB) int {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM namedays").Scan(&count); err != nil {
+		t.Fatal("Failed to count rows:", err)
+	}
+	return count
+}
+
+func TestImportIsIdempotent(t *testing.T) {
+	db := openTestDb(t)
+	data := Namedays{
+		"06-24": {"Jānis", "Jāņa"},
+		"07-26": {"Anna", "Anna"},
+	}
+
+	report, err := Import(db, data, Options{Country: "lv"})
+	if err != nil {
+		t.Fatalf("Import returned an error: %v", err)
+	}
+	if report.Added != 3 || report.Unchanged != 0 {
+		t.Errorf("Unexpected first report: %+v", report)
+	}
+
+	report, err = Import(db, data, Options{Country: "lv"})
+	if err != nil {
+		t.Fatalf("Second import returned an error: %v", err)
+	}
+	if report.Added != 0 || report.Unchanged != 3 || len(report.Dates) != 0 {
+		t.Errorf("Unexpected second report: %+v", report)
+	}
+	if n := countRows(t, db); n != 3 {
+		t.Errorf("Expected 3 rows, got %d", n)
+	}
+
+	var slug string
+	db.QueryRow("SELECT slug FROM namedays WHERE name = 'Jānis'").Scan(&slug)
+	if slug != "janis" {
+		t.Errorf("Expected slug janis, got %s", slug)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	db := openTestDb(t)
+
+	report, err := Import(db, Namedays{"06-24": {"Jānis"}}, Options{Country: "lv", DryRun: true})
+	if err != nil {
+		t.Fatalf("Import returned an error: %v", err)
+	}
+	if report.Added != 1 || !report.DryRun {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if n := countRows(t, db); n != 0 {
+		t.Errorf("Dry run wrote %d rows", n)
+	}
+}
+
+func TestImportPrune(t *testing.T) {
+	db := openTestDb(t)
+	Import(db, Namedays{"06-24": {"Jānis", "Old"}, "07-26": {"Anna"}}, Options{Country: "lv"})
+	Import(db, Namedays{"06-24": {"Jonas"}}, Options{Country: "lt"})
+
+	next := Namedays{"06-24": {"Jānis", "Līga"}}
+
+	// Without pruning, stale names are reported but kept
+	report, err := Import(db, next, Options{Country: "lv"})
+	if err != nil {
+		t.Fatalf("Import returned an error: %v", err)
+	}
+	if report.Added != 1 || report.Removed != 2 || report.Unchanged != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if n := countRows(t, db); n != 5 {
+		t.Errorf("Expected 5 rows, got %d", n)
+	}
+
+	report, err = Import(db, next, Options{Country: "lv", Prune: true})
+	if err != nil {
+		t.Fatalf("Import returned an error: %v", err)
+	}
+	if report.Removed != 2 || !report.Pruned {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	// Two Latvian names are left, and the Lithuanian calendar is untouched
+	if n := countRows(t, db); n != 3 {
+		t.Errorf("Expected 3 rows, got %d", n)
+	}
+}
+
+func TestImportPruneKeepsAPINames(t *testing.T) {
+	db := openTestDb(t)
+	Import(db, Namedays{"06-24": {"Jānis"}}, Options{Country: "lv"})
+	if _, err := db.Exec("INSERT INTO namedays (date, name, slug, country, source) VALUES ('07-26', 'Anna', 'anna', 'lv', 'api')"); err != nil {
+		t.Fatal("Failed to insert nameday:", err)
+	}
+
+	report, err := Import(db, Namedays{"06-24": {"Jānis"}}, Options{Country: "lv", Prune: true})
+	if err != nil {
+		t.Fatalf("Import returned an error: %v", err)
+	}
+	if report.Removed != 0 || report.Unchanged != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if n := countRows(t, db); n != 2 {
+		t.Errorf("Expected the API name to be kept, got %d rows", n)
+	}
+}
+
+func TestImportRequiresCountry(t *testing.T) {
+	db := openTestDb(t)
+
+	if _, err := Import(db, Namedays{}, Options{}); err == nil {
+		t.Error("Expected an error without a country")
+	}
+}
+
+func TestReportPrint(t *testing.T) {
+	db := openTestDb(t)
+	Import(db, Namedays{"06-24": {"Old"}}, Options{Country: "lv"})
+
+	report, err := Import(db, Namedays{"06-24": {"Jānis"}}, Options{Country: "lv", DryRun: true, Prune: true})
+	if err != nil {
+		t.Fatalf("Import returned an error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	report.Print(&buf)
+	expected := "lv: 1 added, 1 removed, 0 unchanged (dry run)\n06-24\t+ Jānis\n06-24\t- Old\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected output:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "dry run") {
+		t.Error("Expected dry run marker")
+	}
+}
@@ -0,0 +1,172 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Issue severities. Errors make a dataset invalid, warnings only inform.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue codes reported by Validate
+const (
+	IssueInvalidDate        = "invalid_date"
+	IssueEmptyName          = "empty_name"
+	IssueWhitespace         = "whitespace"
+	IssueStrayPunctuation   = "stray_punctuation"
+	IssueEmbeddedSeparator  = "embedded_separator"
+	IssueInvalidCharacter   = "invalid_character"
+	IssueDuplicateInDay     = "duplicate_in_day"
+	IssueDuplicateAcrossDay = "duplicate_across_days"
+)
+
+var monthDayRe = regexp.MustCompile(`^\d{2}-\d{2}$`)
+
+// Issue is a single problem found in a dataset
+type Issue struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Date     string `json:"date"`
+	Name     string `json:"name,omitempty"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport is the machine-readable result of Validate
+type ValidationReport struct {
+	Valid    bool    `json:"valid"`
+	Errors   int     `json:"errors"`
+	Warnings int     `json:"warnings"`
+	Issues   []Issue `json:"issues"`
+}
+
+func (r *ValidationReport) add(severity, code, date, name, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{
+		Severity: severity,
+		Code:     code,
+		Date:     date,
+		Name:     name,
+		Message:  fmt.Sprintf(format, args...),
+	})
+	if severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// ValidDate reports whether s is an "MM-DD" date. 02-29 is accepted.
+func ValidDate(s string) bool {
+	if !monthDayRe.MatchString(s) {
+		return false
+	}
+	_, err := time.Parse("2006-01-02", "2000-"+s)
+	return err == nil
+}
+
+// Validate checks date keys and names of a dataset. Issues are ordered by
+// date and then by their position within the day.
+func Validate(data Namedays) *ValidationReport {
+	report := &ValidationReport{Issues: []Issue{}}
+
+	dates := make([]string, 0, len(data))
+	for date := range data {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	seen := make(map[string]string)
+	for _, date := range dates {
+		if !ValidDate(date) {
+			report.add(SeverityError, IssueInvalidDate, date, "", "%q is not a valid MM-DD date", date)
+		}
+
+		inDay := make(map[string]bool)
+		for _, name := range data[date] {
+			validateName(report, date, name)
+
+			cleaned := strings.TrimSpace(name)
+			if inDay[cleaned] {
+				report.add(SeverityError, IssueDuplicateInDay, date, name, "%q is listed more than once on %s", cleaned, date)
+				continue
+			}
+			inDay[cleaned] = true
+
+			if first, ok := seen[cleaned]; ok && cleaned != "" {
+				report.add(SeverityWarning, IssueDuplicateAcrossDay, date, name, "%q is also listed on %s", cleaned, first)
+			} else {
+				seen[cleaned] = date
+			}
+		}
+	}
+
+	report.Valid = report.Errors == 0
+	return report
+}
+
+func validateName(report *ValidationReport, date, name string) {
+	trimmed := strings.TrimSpace(name)
+	if trimmed != name {
+		report.add(SeverityError, IssueWhitespace, date, name, "%q has leading or trailing whitespace", name)
+	}
+
+	stripped := strings.TrimFunc(trimmed, isStray)
+	if stripped == "" {
+		report.add(SeverityError, IssueEmptyName, date, name, "%q does not contain a name", name)
+		return
+	}
+	if stripped != trimmed {
+		report.add(SeverityError, IssueStrayPunctuation, date, name, "%q has stray punctuation", name)
+	}
+
+	if strings.ContainsAny(stripped, ",;") {
+		report.add(SeverityError, IssueEmbeddedSeparator, date, name, "%q looks like several names in one entry", name)
+		return
+	}
+
+	for _, r := range stripped {
+		if !isNameRune(r) {
+			report.add(SeverityError, IssueInvalidCharacter, date, name, "%q contains the character %q", name, r)
+			return
+		}
+	}
+}
+
+// isStray matches characters that never start or end a name
+func isStray(r rune) bool {
+	return unicode.IsSpace(r) || (unicode.IsPunct(r) && r != '\'') || unicode.IsSymbol(r)
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || r == '-' || r == '\'' || r == ' '
+}
+
+// Clean fixes what Validate can fix on its own: whitespace and stray
+// punctuation are trimmed, entries holding several names are split, and
+// empty entries and duplicates within a day are dropped. Invalid dates are
+// kept for a human to resolve.
+func Clean(data Namedays) Namedays {
+	cleaned := make(Namedays, len(data))
+	for date, names := range data {
+		inDay := make(map[string]bool)
+		result := []string{}
+		for _, entry := range names {
+			for _, part := range strings.FieldsFunc(entry, func(r rune) bool { return r == ',' || r == ';' }) {
+				name := strings.TrimFunc(part, isStray)
+				if name == "" || inDay[name] {
+					continue
+				}
+				inDay[name] = true
+				result = append(result, name)
+			}
+		}
+		cleaned[date] = result
+	}
+	return cleaned
+}
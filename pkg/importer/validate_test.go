@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/zrks/namedays/data"
+)
+
+func issueCodes(report *ValidationReport) map[string]int {
+	codes := make(map[string]int)
+	for _, issue := range report.Issues {
+		codes[issue.Code]++
+	}
+	return codes
+}
+
+func TestValidDate(t *testing.T) {
+	for _, s := range []string{"01-01", "02-29", "12-31"} {
+		if !ValidDate(s) {
+			t.Errorf("ValidDate(%q) returned false", s)
+		}
+	}
+	for _, s := range []string{"02-30", "13-01", "00-01", "1-01", "banana"} {
+		if ValidDate(s) {
+			t.Errorf("ValidDate(%q) returned true", s)
+		}
+	}
+}
+
+func TestValidateCleanDataset(t *testing.T) {
+	report := Validate(Namedays{
+		"02-29": {"Leap"},
+		"06-24": {"Jānis", "Jāņa"},
+		"09-30": {"Anna-Marija", "O'Neil"},
+	})
+
+	if !report.Valid || len(report.Issues) != 0 {
+		t.Errorf("Expected a valid dataset, got %+v", report)
+	}
+}
+
+func TestValidateFindsProblems(t *testing.T) {
+	report := Validate(Namedays{
+		"13-40": {"Nobody"},
+		"01-11": {"Smaidiņa,", "Lillija ", "–", "Kazmirina,Sidars", "R2D2", "Anna", "Anna"},
+		"07-26": {"Anna"},
+	})
+
+	if report.Valid {
+		t.Fatal("Expected an invalid dataset")
+	}
+
+	expected := map[string]int{
+		IssueInvalidDate:        1,
+		IssueStrayPunctuation:   1,
+		IssueWhitespace:         1,
+		IssueEmptyName:          1,
+		IssueEmbeddedSeparator:  1,
+		IssueInvalidCharacter:   1,
+		IssueDuplicateInDay:     1,
+		IssueDuplicateAcrossDay: 1,
+	}
+	if codes := issueCodes(report); !reflect.DeepEqual(codes, expected) {
+		t.Errorf("Unexpected issues: got %v want %v", codes, expected)
+	}
+	if report.Warnings != 1 || report.Errors != 7 {
+		t.Errorf("Expected 7 errors and 1 warning, got %d and %d", report.Errors, report.Warnings)
+	}
+
+	// The report must be machine-readable
+	if _, err := json.Marshal(report); err != nil {
+		t.Errorf("Failed to marshal report: %v", err)
+	}
+}
+
+func TestValidateBundledDataset(t *testing.T) {
+	jsonData, err := data.FS.ReadFile("lv.json")
+	if err != nil {
+		t.Fatal("Failed to read bundled dataset:", err)
+	}
+	var namedays Namedays
+	if err = json.Unmarshal(jsonData, &namedays); err != nil {
+		t.Fatal("Failed to parse bundled dataset:", err)
+	}
+
+	// The known junk in the bundled data must be caught
+	found := false
+	for _, issue := range Validate(namedays).Issues {
+		if issue.Date == "01-11" && issue.Name == "Smaidiņa," && issue.Code == IssueStrayPunctuation {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the trailing comma in Smaidiņa, to be reported")
+	}
+
+	if report := Validate(Clean(namedays)); !report.Valid {
+		t.Errorf("Expected the cleaned dataset to be valid, got %+v", report.Issues)
+	}
+}
+
+func TestClean(t *testing.T) {
+	cleaned := Clean(Namedays{
+		"01-11": {"Smaidiņa,", " Lillija ", "–", "Kazmirina,Sidars", "Anna", "Anna"},
+	})
+
+	expected := Namedays{"01-11": {"Smaidiņa", "Lillija", "Kazmirina", "Sidars", "Anna"}}
+	if !reflect.DeepEqual(cleaned, expected) {
+		t.Errorf("Unexpected cleaned dataset: got %v want %v", cleaned, expected)
+	}
+}
@@ -0,0 +1,364 @@
+// Package migrations holds the versioned schema of the namedays database
+// and applies it in order, recording progress in schema_migrations.
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	UnknownVersionErr = errors.New("unknown migration version")
+)
+
+// Migration is a single reversible schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+	Down    func(tx *sql.Tx) error
+}
+
+// Status describes whether a migration has been applied to a database
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt string
+}
+
+// All lists every known migration in version order
+var All = []Migration{
+	{
+		Version: 1,
+		Name:    "create_namedays",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, `CREATE TABLE IF NOT EXISTS namedays (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT NOT NULL, name TEXT NOT NULL);`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS namedays;`)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add_namedays_slug",
+		Up:      addSlugColumn,
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`DROP INDEX IF EXISTS idx_namedays_slug;`,
+				`ALTER TABLE namedays DROP COLUMN slug;`,
+			)
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add_namedays_country",
+		Up: func(tx *sql.Tx) error {
+			// Every row that predates this migration is from the Latvian calendar
+			return execAll(tx,
+				`ALTER TABLE namedays ADD COLUMN country TEXT NOT NULL DEFAULT 'lv';`,
+				`CREATE INDEX IF NOT EXISTS idx_namedays_country_date ON namedays (country, date);`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`DROP INDEX IF EXISTS idx_namedays_country_date;`,
+				`ALTER TABLE namedays DROP COLUMN country;`,
+			)
+		},
+	},
+	{
+		Version: 4,
+		Name:    "unique_namedays",
+		Up: func(tx *sql.Tx) error {
+			// Repeated imports used to duplicate every row, keep the oldest copy
+			return execAll(tx,
+				`DELETE FROM namedays WHERE id NOT IN (SELECT MIN(id) FROM namedays GROUP BY country, date, name);`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS idx_namedays_unique ON namedays (country, date, name);`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP INDEX IF EXISTS idx_namedays_unique;`)
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add_namedays_gender",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, `ALTER TABLE namedays ADD COLUMN gender TEXT NOT NULL DEFAULT '';`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `ALTER TABLE namedays DROP COLUMN gender;`)
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create_contacts",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS contacts (id INTEGER PRIMARY KEY AUTOINCREMENT, list TEXT NOT NULL, name TEXT NOT NULL, first_name TEXT NOT NULL, slug TEXT NOT NULL, email TEXT NOT NULL DEFAULT '');`,
+				`CREATE INDEX IF NOT EXISTS idx_contacts_list ON contacts (list, slug);`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS contacts;`)
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create_webhooks",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS webhooks (id INTEGER PRIMARY KEY AUTOINCREMENT, url TEXT NOT NULL, secret TEXT NOT NULL, country TEXT NOT NULL, list TEXT NOT NULL DEFAULT '', created_at TEXT NOT NULL);`,
+				`CREATE TABLE IF NOT EXISTS webhook_deliveries (id INTEGER PRIMARY KEY AUTOINCREMENT, webhook_id INTEGER NOT NULL, day TEXT NOT NULL, attempt INTEGER NOT NULL, status INTEGER NOT NULL, success INTEGER NOT NULL, error TEXT NOT NULL DEFAULT '', created_at TEXT NOT NULL);`,
+				`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, day);`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS webhook_deliveries;`,
+				`DROP TABLE IF EXISTS webhooks;`,
+			)
+		},
+	},
+	{
+		Version: 8,
+		Name:    "add_webhooks_format",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, `ALTER TABLE webhooks ADD COLUMN format TEXT NOT NULL DEFAULT 'json';`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `ALTER TABLE webhooks DROP COLUMN format;`)
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create_subscribers",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, `CREATE TABLE IF NOT EXISTS subscribers (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT NOT NULL UNIQUE, country TEXT NOT NULL, frequency TEXT NOT NULL, token TEXT NOT NULL UNIQUE, confirmed INTEGER NOT NULL DEFAULT 0, last_sent TEXT NOT NULL DEFAULT '', created_at TEXT NOT NULL);`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS subscribers;`)
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add_namedays_source",
+		Up: func(tx *sql.Tx) error {
+			// Rows written through the API are marked "api" so that pruning
+			// a dataset import leaves them alone
+			return execAll(tx, `ALTER TABLE namedays ADD COLUMN source TEXT NOT NULL DEFAULT 'dataset';`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `ALTER TABLE namedays DROP COLUMN source;`)
+		},
+	},
+}
+
+// Latest returns the highest known migration version
+func Latest() int {
+	if len(All) == 0 {
+		return 0
+	}
+	return All[len(All)-1].Version
+}
+
+// Up applies every pending migration
+func Up(db *sql.DB) error {
+	return Migrate(db, Latest())
+}
+
+// Down reverts the given number of most recently applied migrations
+func Down(db *sql.DB, steps int) error {
+	current, err := Current(db)
+	if err != nil {
+		return err
+	}
+
+	target := 0
+	for i := len(All) - 1; i >= 0; i-- {
+		if All[i].Version > current {
+			continue
+		}
+		if steps == 0 {
+			target = All[i].Version
+			break
+		}
+		steps--
+	}
+
+	return Migrate(db, target)
+}
+
+// Migrate moves the schema up or down until target is the latest applied
+// version. A target of 0 reverts every migration.
+func Migrate(db *sql.DB, target int) error {
+	if target != 0 && find(target) < 0 {
+		return fmt.Errorf("%w: %d", UnknownVersionErr, target)
+	}
+
+	if err := ensureTable(db); err != nil {
+		return err
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range All {
+		if m.Version > target || applied[m.Version] != "" {
+			continue
+		}
+		if err := run(db, m, true); err != nil {
+			return err
+		}
+	}
+
+	for i := len(All) - 1; i >= 0; i-- {
+		m := All[i]
+		if m.Version <= target || applied[m.Version] == "" {
+			continue
+		}
+		if err := run(db, m, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Current returns the highest applied migration version, or 0 for an
+// empty database
+func Current(db *sql.DB) (int, error) {
+	if err := ensureTable(db); err != nil {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// List reports the state of every known migration
+func List(db *sql.DB) ([]Status, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Status, 0, len(All))
+	for _, m := range All {
+		result = append(result, Status{
+			Version:   m.Version,
+			Name:      m.Name,
+			Applied:   applied[m.Version] != "",
+			AppliedAt: applied[m.Version],
+		})
+	}
+	return result, nil
+}
+
+func run(db *sql.DB, m Migration, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if up {
+		err = m.Up(tx)
+		if err == nil {
+			_, err = tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+		}
+	} else {
+		err = m.Down(tx)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+func ensureTable(db *sql.DB) error {
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at TEXT NOT NULL);`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+func appliedVersions(db *sql.DB) (map[int]string, error) {
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func find(version int) int {
+	i := sort.Search(len(All), func(i int) bool { return All[i].Version >= version })
+	if i < len(All) && All[i].Version == version {
+		return i
+	}
+	return -1
+}
+
+func execAll(tx *sql.Tx, statements ...string) error {
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasColumn reports whether table already has the named column, which lets
+// migrations adopt databases created before schema_migrations existed
+func hasColumn(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -0,0 +1,156 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestDb(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "namedays.db"))
+	if err != nil {
+		t.Fatal("Failed to open database:", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func columnExists(t *testing.T, db *sql.DB, column string) bool {
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal("Failed to begin transaction:", err)
+	}
+	defer tx.Rollback()
+
+	exists, err := hasColumn(tx, "namedays", column)
+	if err != nil {
+		t.Fatal("Failed to read table info:", err)
+	}
+	return exists
+}
+
+func TestUpAppliesAllMigrations(t *testing.T) {
+	db := openTestDb(t)
+
+	if err := Up(db); err != nil {
+		t.Fatalf("Up returned an error: %v", err)
+	}
+
+	current, err := Current(db)
+	if err != nil {
+		t.Fatalf("Current returned an error: %v", err)
+	}
+	if current != Latest() {
+		t.Errorf("Expected version %d, got %d", Latest(), current)
+	}
+	for _, column := range []string{"slug", "country"} {
+		if !columnExists(t, db, column) {
+			t.Errorf("Expected %s column to exist", column)
+		}
+	}
+
+	// Running again must be a no-op
+	if err := Up(db); err != nil {
+		t.Fatalf("Second Up returned an error: %v", err)
+	}
+}
+
+func TestDownRevertsMigrations(t *testing.T) {
+	db := openTestDb(t)
+	if err := Up(db); err != nil {
+		t.Fatalf("Up returned an error: %v", err)
+	}
+
+	if err := Down(db, 1); err != nil {
+		t.Fatalf("Down returned an error: %v", err)
+	}
+	current, _ := Current(db)
+	if current != Latest()-1 {
+		t.Errorf("Expected version %d, got %d", Latest()-1, current)
+	}
+
+	if err := Migrate(db, 0); err != nil {
+		t.Fatalf("Migrate to 0 returned an error: %v", err)
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'namedays'").Scan(&count)
+	if count != 0 {
+		t.Error("Expected namedays table to be dropped")
+	}
+
+	statuses, err := List(db)
+	if err != nil {
+		t.Fatalf("List returned an error: %v", err)
+	}
+	for _, s := range statuses {
+		if s.Applied {
+			t.Errorf("Expected migration %d to be reverted", s.Version)
+		}
+	}
+}
+
+func TestUpAdoptsLegacyDatabase(t *testing.T) {
+	db := openTestDb(t)
+
+	// A database created before migrations existed
+	if _, err := db.Exec(`CREATE TABLE namedays (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT NOT NULL, name TEXT NOT NULL);`); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	if _, err := db.Exec("INSERT INTO namedays (date, name) VALUES (?, ?)", "06-24", "Jānis"); err != nil {
+		t.Fatal("Failed to insert test data:", err)
+	}
+
+	if err := Up(db); err != nil {
+		t.Fatalf("Up returned an error: %v", err)
+	}
+
+	var slug, country string
+	if err := db.QueryRow("SELECT slug, country FROM namedays WHERE date = '06-24'").Scan(&slug, &country); err != nil {
+		t.Fatal("Failed to read row:", err)
+	}
+	if slug != "janis" {
+		t.Errorf("Expected slug janis, got %s", slug)
+	}
+	if country != "lv" {
+		t.Errorf("Expected country lv, got %s", country)
+	}
+}
+
+func TestUniqueMigrationRemovesDuplicates(t *testing.T) {
+	db := openTestDb(t)
+	if err := Migrate(db, 3); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+
+	// Importing twice used to duplicate every row
+	for i := 0; i < 2; i++ {
+		if _, err := db.Exec("INSERT INTO namedays (date, name, slug) VALUES ('06-24', 'Jānis', 'janis')"); err != nil {
+			t.Fatal("Failed to insert test data:", err)
+		}
+	}
+
+	if err := Up(db); err != nil {
+		t.Fatalf("Up returned an error: %v", err)
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM namedays").Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected duplicates to be removed, got %d rows", count)
+	}
+
+	if _, err := db.Exec("INSERT INTO namedays (date, name, slug) VALUES ('06-24', 'Jānis', 'janis')"); err == nil {
+		t.Error("Expected the unique constraint to reject a duplicate")
+	}
+}
+
+func TestMigrateUnknownVersion(t *testing.T) {
+	db := openTestDb(t)
+
+	if err := Migrate(db, Latest()+100); !errors.Is(err, UnknownVersionErr) {
+		t.Errorf("Expected UnknownVersionErr, got %v", err)
+	}
+}
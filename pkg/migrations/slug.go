@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/gosimple/slug"
+)
+
+// addSlugColumn adds the slug lookup column and fills it in for existing rows
+func addSlugColumn(tx *sql.Tx) error {
+	exists, err := hasColumn(tx, "namedays", "slug")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err = execAll(tx, `ALTER TABLE namedays ADD COLUMN slug TEXT NOT NULL DEFAULT '';`); err != nil {
+			return err
+		}
+	}
+
+	if err = execAll(tx, `CREATE INDEX IF NOT EXISTS idx_namedays_slug ON namedays (slug);`); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT id, name FROM namedays WHERE slug = ''")
+	if err != nil {
+		return err
+	}
+
+	slugs := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		slugs[id] = slug.Make(name)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for id, s := range slugs {
+		if _, err = tx.Exec("UPDATE namedays SET slug = ? WHERE id = ?", s, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
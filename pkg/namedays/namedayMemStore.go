@@ -0,0 +1,99 @@
+package namedays
+
+import (
+	"sync"
+)
+
+// memKey is a key within one calendar
+type memKey struct {
+	country string
+	name    string
+}
+
+// MemStore is an in-memory Store
+type MemStore struct {
+	mu             sync.RWMutex
+	list           map[memKey]Nameday
+	defaultCountry string
+}
+
+// NewMemStore returns a store saving namedays without a country in the
+// defaultCountry calendar
+func NewMemStore(defaultCountry string) *MemStore {
+	return &MemStore{
+		list:           make(map[memKey]Nameday),
+		defaultCountry: defaultCountry,
+	}
+}
+
+// key returns the key of name in a calendar, the default one when country
+// is empty
+func (m *MemStore) key(country, name string) memKey {
+	if country == "" {
+		country = m.defaultCountry
+	}
+	return memKey{country: country, name: name}
+}
+
+func (m *MemStore) Add(name string, nameday Nameday) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := m.key(nameday.Country, name)
+	if _, ok := m.list[key]; ok {
+		return ExistsErr
+	}
+	nameday.Country = key.country
+	m.list[key] = nameday
+	return nil
+}
+
+func (m *MemStore) Get(country, name string) (Nameday, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if val, ok := m.list[m.key(country, name)]; ok {
+		return val, nil
+	}
+	return Nameday{}, NotFoundErr
+}
+
+// List returns a copy of the stored namedays, so callers may modify it
+// without affecting the store. Across every calendar, a key found in
+// several other calendars resolves to the alphabetically first country.
+func (m *MemStore) List(country string) (map[string]Nameday, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make(map[string]Nameday, len(m.list))
+	for key, nameday := range m.list {
+		if country != "" && key.country != country {
+			continue
+		}
+		if current, ok := result[key.name]; ok && (current.Country == m.defaultCountry || (key.country != m.defaultCountry && current.Country < key.country)) {
+			continue
+		}
+		result[key.name] = nameday
+	}
+	return result, nil
+}
+
+func (m *MemStore) Update(country, name string, nameday Nameday) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := m.key(country, name)
+	if _, ok := m.list[key]; ok {
+		nameday.Country = key.country
+		m.list[key] = nameday
+		return nil
+	}
+	return NotFoundErr
+}
+
+func (m *MemStore) Remove(country, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := m.key(country, name)
+	if _, ok := m.list[key]; ok {
+		delete(m.list, key)
+		return nil
+	}
+	return NotFoundErr
+}
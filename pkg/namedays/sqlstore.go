@@ -0,0 +1,123 @@
+package namedays
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SQLStore is a Store backed by the namedays table of the namedays
+// database, as created by the github.com/zrks/namedays/pkg/migrations
+// package. A slug may appear on several dates of a calendar in an imported
+// dataset, Get and List then return the earliest one. Rows written by the store have the
+// "api" source, which dataset imports never prune.
+type SQLStore struct {
+	db             *sql.DB
+	defaultCountry string
+}
+
+// NewSQLStore returns a store saving namedays without a country in the
+// defaultCountry calendar
+func NewSQLStore(db *sql.DB, defaultCountry string) *SQLStore {
+	return &SQLStore{db: db, defaultCountry: defaultCountry}
+}
+
+// country returns the calendar named by country, the default one when it
+// is empty
+func (s *SQLStore) country(country string) string {
+	if country == "" {
+		return s.defaultCountry
+	}
+	return country
+}
+
+func (s *SQLStore) Add(name string, nameday Nameday) error {
+	country := s.country(nameday.Country)
+	// The existence check and the insert are a single statement so that
+	// concurrent adds of the same key cannot both succeed
+	result, err := s.db.Exec("INSERT INTO namedays (date, name, slug, country, source) SELECT ?, ?, ?, ?, 'api' WHERE NOT EXISTS (SELECT 1 FROM namedays WHERE slug = ? AND country = ?)",
+		nameday.Date, nameday.Name, name, country, name, country)
+	if err != nil {
+		return fmt.Errorf("failed to insert nameday: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ExistsErr
+	}
+	return nil
+}
+
+func (s *SQLStore) Get(country, name string) (Nameday, error) {
+	var nameday Nameday
+	err := s.db.QueryRow("SELECT name, date, country FROM namedays WHERE slug = ? AND country = ? ORDER BY date, id LIMIT 1", name, s.country(country)).Scan(&nameday.Name, &nameday.Date, &nameday.Country)
+	if err == sql.ErrNoRows {
+		return Nameday{}, NotFoundErr
+	}
+	if err != nil {
+		return Nameday{}, fmt.Errorf("error querying database: %w", err)
+	}
+	return nameday, nil
+}
+
+func (s *SQLStore) List(country string) (map[string]Nameday, error) {
+	// Later rows overwrite earlier ones, so the default calendar is read
+	// last and dates in descending order: when a slug appears on several
+	// dates the earliest one wins just like in Get. A slug found only in
+	// other calendars resolves to the alphabetically first country.
+	rows, err := s.db.Query("SELECT slug, name, date, country FROM namedays WHERE ? = '' OR country = ? ORDER BY country = ?, country DESC, date DESC, id DESC",
+		country, country, s.defaultCountry)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]Nameday)
+	for rows.Next() {
+		var key string
+		var nameday Nameday
+		if err := rows.Scan(&key, &nameday.Name, &nameday.Date, &nameday.Country); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		result[key] = nameday
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return result, nil
+}
+
+func (s *SQLStore) Update(country, name string, nameday Nameday) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Collapse every row for the slug in the calendar into a single entry
+	country = s.country(country)
+	result, err := tx.Exec("DELETE FROM namedays WHERE slug = ? AND country = ?", name, country)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update nameday: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return NotFoundErr
+	}
+	if _, err = tx.Exec("INSERT INTO namedays (date, name, slug, country, source) VALUES (?, ?, ?, ?, 'api')", nameday.Date, nameday.Name, name, country); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update nameday: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLStore) Remove(country, name string) error {
+	result, err := s.db.Exec("DELETE FROM namedays WHERE slug = ? AND country = ?", name, s.country(country))
+	if err != nil {
+		return fmt.Errorf("failed to remove nameday: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return NotFoundErr
+	}
+	return nil
+}
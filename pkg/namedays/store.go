@@ -0,0 +1,35 @@
+// Package namedays is the nameday store shared by the namedays server and
+// any Go service that wants to embed nameday lookups without going through
+// HTTP. Namedays are keyed by the slug of the name.
+package namedays
+
+import "errors"
+
+var (
+	NotFoundErr = errors.New("not found")
+	ExistsErr   = errors.New("already exists")
+)
+
+// Nameday is a name and the date ("MM-DD") it is celebrated on. An empty
+// Country means the store's default calendar.
+type Nameday struct {
+	Name    string `json:"name"`
+	Date    string `json:"date"`
+	Country string `json:"country,omitempty"`
+}
+
+// Store is implemented by every nameday store. Keys are scoped to a
+// calendar, so the same key may exist in several countries. Add acts on
+// the nameday's country, the other methods on the country they are given,
+// and an empty country means the store's default one. List with an empty
+// country returns one nameday per key across every calendar, preferring
+// the default one. Add fails with ExistsErr when the key is taken, Get,
+// Update and Remove fail with NotFoundErr when it is missing.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	Add(name string, nameday Nameday) error
+	Get(country, name string) (Nameday, error)
+	List(country string) (map[string]Nameday, error)
+	Update(country, name string, nameday Nameday) error
+	Remove(country, name string) error
+}
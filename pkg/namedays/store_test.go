@@ -0,0 +1,282 @@
+package namedays
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/zrks/namedays/pkg/migrations"
+)
+
+// testStore is the contract every Store implementation must satisfy. The
+// concurrent case is meant to be run with -race.
+func testStore(t *testing.T, newStore func(t *testing.T) Store) {
+	anna := Nameday{Name: "Anna", Date: "07-26", Country: "lv"}
+
+	t.Run("AddGet", func(t *testing.T) {
+		store := newStore(t)
+		if err := store.Add("anna", anna); err != nil {
+			t.Fatalf("Add returned an error: %v", err)
+		}
+		retrieved, err := store.Get("", "anna")
+		if err != nil {
+			t.Fatalf("Get returned an error: %v", err)
+		}
+		if retrieved != anna {
+			t.Errorf("Expected %v, got %v", anna, retrieved)
+		}
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		store := newStore(t)
+		if _, err := store.Get("", "nobody"); !errors.Is(err, NotFoundErr) {
+			t.Errorf("Expected NotFoundErr, got %v", err)
+		}
+	})
+
+	t.Run("AddExisting", func(t *testing.T) {
+		store := newStore(t)
+		if err := store.Add("anna", anna); err != nil {
+			t.Fatalf("Add returned an error: %v", err)
+		}
+		other := Nameday{Name: "Anna", Date: "12-09", Country: "lv"}
+		if err := store.Add("anna", other); !errors.Is(err, ExistsErr) {
+			t.Errorf("Expected ExistsErr, got %v", err)
+		}
+		if retrieved, _ := store.Get("", "anna"); retrieved != anna {
+			t.Errorf("Expected %v to be kept, got %v", anna, retrieved)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		store := newStore(t)
+		if err := store.Add("anna", anna); err != nil {
+			t.Fatalf("Add returned an error: %v", err)
+		}
+		updated := Nameday{Name: "Anna", Date: "12-09", Country: "lv"}
+		if err := store.Update("", "anna", updated); err != nil {
+			t.Fatalf("Update returned an error: %v", err)
+		}
+		if retrieved, _ := store.Get("", "anna"); retrieved != updated {
+			t.Errorf("Expected %v, got %v", updated, retrieved)
+		}
+	})
+
+	t.Run("UpdateMissing", func(t *testing.T) {
+		store := newStore(t)
+		if err := store.Update("", "nobody", anna); !errors.Is(err, NotFoundErr) {
+			t.Errorf("Expected NotFoundErr, got %v", err)
+		}
+		if _, err := store.Get("", "nobody"); !errors.Is(err, NotFoundErr) {
+			t.Errorf("Expected Update not to create the nameday, got %v", err)
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		store := newStore(t)
+		if err := store.Add("anna", anna); err != nil {
+			t.Fatalf("Add returned an error: %v", err)
+		}
+		if err := store.Remove("", "anna"); err != nil {
+			t.Fatalf("Remove returned an error: %v", err)
+		}
+		if _, err := store.Get("", "anna"); !errors.Is(err, NotFoundErr) {
+			t.Errorf("Expected NotFoundErr for a removed nameday, got %v", err)
+		}
+		if err := store.Remove("", "anna"); !errors.Is(err, NotFoundErr) {
+			t.Errorf("Expected NotFoundErr removing twice, got %v", err)
+		}
+	})
+
+	t.Run("SameKeyInTwoCountries", func(t *testing.T) {
+		store := newStore(t)
+		ona := Nameday{Name: "Anna", Date: "02-01", Country: "lt"}
+		if err := store.Add("anna", ona); err != nil {
+			t.Fatalf("Add in lt returned an error: %v", err)
+		}
+		if _, err := store.Get("", "anna"); !errors.Is(err, NotFoundErr) {
+			t.Errorf("Expected the default calendar to be empty, got %v", err)
+		}
+		if retrieved, err := store.Get("lt", "anna"); err != nil || retrieved != ona {
+			t.Errorf("Get in lt returned %v, %v", retrieved, err)
+		}
+		if err := store.Add("anna", Nameday{Name: "Anna", Date: "07-26"}); err != nil {
+			t.Fatalf("Add in the default calendar returned an error: %v", err)
+		}
+		if list, _ := store.List(""); list["anna"] != anna {
+			t.Errorf("Expected List to prefer the default calendar, got %v", list["anna"])
+		}
+		if list, _ := store.List("lt"); len(list) != 1 || list["anna"] != ona {
+			t.Errorf("Expected List in lt to only hold lt, got %v", list)
+		}
+
+		updated := Nameday{Name: "Anna", Date: "02-02", Country: "lt"}
+		if err := store.Update("lt", "anna", Nameday{Name: "Anna", Date: "02-02"}); err != nil {
+			t.Fatalf("Update in lt returned an error: %v", err)
+		}
+		if retrieved, _ := store.Get("lt", "anna"); retrieved != updated {
+			t.Errorf("Expected %v, got %v", updated, retrieved)
+		}
+		if retrieved, _ := store.Get("", "anna"); retrieved != anna {
+			t.Errorf("Expected the default calendar to be untouched by Update, got %v", retrieved)
+		}
+
+		if err := store.Remove("lt", "anna"); err != nil {
+			t.Fatalf("Remove in lt returned an error: %v", err)
+		}
+		if _, err := store.Get("lt", "anna"); !errors.Is(err, NotFoundErr) {
+			t.Errorf("Expected NotFoundErr in lt, got %v", err)
+		}
+		if retrieved, _ := store.Get("lv", "anna"); retrieved != anna {
+			t.Errorf("Expected Remove to keep the default calendar, got %v", retrieved)
+		}
+		if err := store.Remove("lt", "anna"); !errors.Is(err, NotFoundErr) {
+			t.Errorf("Expected NotFoundErr removing again, got %v", err)
+		}
+	})
+
+	t.Run("ListReturnsCopy", func(t *testing.T) {
+		store := newStore(t)
+		if err := store.Add("anna", anna); err != nil {
+			t.Fatalf("Add returned an error: %v", err)
+		}
+
+		list, err := store.List("")
+		if err != nil {
+			t.Fatalf("List returned an error: %v", err)
+		}
+		if len(list) != 1 || list["anna"] != anna {
+			t.Fatalf("Expected only Anna, got %v", list)
+		}
+		delete(list, "anna")
+		list["janis"] = Nameday{Name: "Jānis", Date: "06-24"}
+
+		if list, _ = store.List(""); len(list) != 1 || list["anna"] != anna {
+			t.Errorf("Expected the store to be unaffected by changes to List, got %v", list)
+		}
+	})
+
+	t.Run("ConcurrentCRUD", func(t *testing.T) {
+		store := newStore(t)
+		const workers, iterations = 8, 20
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < iterations; i++ {
+					key := fmt.Sprintf("worker-%d-%d", w, i)
+					nameday := Nameday{Name: key, Date: "05-05", Country: "lv"}
+					if err := store.Add(key, nameday); err != nil {
+						t.Errorf("Add(%s) returned an error: %v", key, err)
+						return
+					}
+					if retrieved, err := store.Get("lv", key); err != nil || retrieved != nameday {
+						t.Errorf("Get(%s) returned %v, %v", key, retrieved, err)
+					}
+					if err := store.Update("lv", key, Nameday{Name: key, Date: "06-06", Country: "lv"}); err != nil {
+						t.Errorf("Update(%s) returned an error: %v", key, err)
+					}
+					if _, err := store.List(""); err != nil {
+						t.Errorf("List returned an error: %v", err)
+					}
+					if i%2 == 0 {
+						if err := store.Remove("lv", key); err != nil {
+							t.Errorf("Remove(%s) returned an error: %v", key, err)
+						}
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		list, err := store.List("")
+		if err != nil {
+			t.Fatalf("List returned an error: %v", err)
+		}
+		if len(list) != workers*iterations/2 {
+			t.Errorf("Expected %d namedays, got %d", workers*iterations/2, len(list))
+		}
+		for key, nameday := range list {
+			if nameday.Date != "06-06" {
+				t.Errorf("Expected %s to be updated, got %v", key, nameday)
+			}
+		}
+	})
+}
+
+func openTestDb(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "namedays.db"))
+	if err != nil {
+		t.Fatal("Failed to open database:", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err = migrations.Up(db); err != nil {
+		t.Fatal("Failed to migrate database:", err)
+	}
+	return db
+}
+
+func TestMemStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		return NewMemStore("lv")
+	})
+}
+
+func TestSQLStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		return NewSQLStore(openTestDb(t), "lv")
+	})
+}
+
+func TestSQLStoreDefaultCountry(t *testing.T) {
+	store := NewSQLStore(openTestDb(t), "lt")
+
+	if err := store.Add("ona", Nameday{Name: "Ona", Date: "02-01"}); err != nil {
+		t.Fatalf("Add returned an error: %v", err)
+	}
+	retrieved, err := store.Get("", "ona")
+	if err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+	if retrieved.Country != "lt" {
+		t.Errorf("Expected the default country lt, got %q", retrieved.Country)
+	}
+}
+
+func TestSQLStoreCollapsesDates(t *testing.T) {
+	db := openTestDb(t)
+	store := NewSQLStore(db, "lv")
+
+	// Imported datasets may list a name on several dates
+	for _, date := range []string{"11-10", "03-12"} {
+		if _, err := db.Exec("INSERT INTO namedays (date, name, slug, country) VALUES (?, 'Mārtiņš', 'martins', 'lv')", date); err != nil {
+			t.Fatal("Failed to insert test data:", err)
+		}
+	}
+
+	if retrieved, _ := store.Get("", "martins"); retrieved.Date != "03-12" {
+		t.Errorf("Expected the earliest date 03-12, got %v", retrieved)
+	}
+	if list, _ := store.List(""); list["martins"].Date != "03-12" {
+		t.Errorf("Expected List to agree with Get, got %v", list["martins"])
+	}
+	if err := store.Add("martins", Nameday{Name: "Mārtiņš", Date: "01-01"}); !errors.Is(err, ExistsErr) {
+		t.Errorf("Expected ExistsErr, got %v", err)
+	}
+
+	if err := store.Update("", "martins", Nameday{Name: "Mārtiņš", Date: "11-10"}); err != nil {
+		t.Fatalf("Update returned an error: %v", err)
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM namedays WHERE slug = 'martins'").Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected Update to leave a single row, got %d", count)
+	}
+}
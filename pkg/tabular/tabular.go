@@ -0,0 +1,140 @@
+// Package tabular reads CSV and TSV files row by row, recognising an
+// optional header row and collecting the rows that are rejected.
+package tabular
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RowError describes why a row of a tabular file was rejected
+type RowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// RowErrors collects every rejected row of a file
+type RowErrors []RowError
+
+func (e RowErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, rowErr := range e {
+		messages = append(messages, rowErr.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Columns maps column names to their position in a row
+type Columns map[string]int
+
+// Format describes the layout of a tabular file
+type Format struct {
+	// Comma separates the fields, ',' for CSV and '\t' for TSV
+	Comma rune
+	// Columns lists the column names a header row may use, in any order.
+	// Header names are matched case-insensitively, with spaces and
+	// hyphens read as underscores.
+	Columns []string
+	// Defaults are the column positions of a file without a header row
+	Defaults Columns
+	// IsHeader reports whether a first row naming these columns is a
+	// header rather than data
+	IsHeader func(columns Columns) bool
+}
+
+// Row is a single data row of a tabular file
+type Row struct {
+	Line    int
+	Fields  []string
+	Columns Columns
+}
+
+// Field returns the trimmed value of a column, or "" when the row does not
+// have it
+func (r Row) Field(column string) string {
+	i, ok := r.Columns[column]
+	if !ok || i >= len(r.Fields) {
+		return ""
+	}
+	return strings.TrimSpace(r.Fields[i])
+}
+
+// Read calls fn with every data row of r. A leading UTF-8 BOM is skipped,
+// as are blank lines. Rows that are not valid CSV and rows fn returns an
+// error for are reported together in the returned RowErrors once the whole
+// file is read.
+func Read(r io.Reader, format Format, fn func(row Row) error) error {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\ufeff" {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = format.Comma
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if format.Comma == '\t' {
+		reader.LazyQuotes = true
+	}
+
+	columns := format.Defaults
+	var rowErrors RowErrors
+	first := true
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if parseErr, ok := err.(*csv.ParseError); ok {
+				rowErrors = append(rowErrors, RowError{Line: parseErr.Line, Message: parseErr.Err.Error()})
+				continue
+			}
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first {
+			first = false
+			if header := format.header(fields); format.IsHeader(header) {
+				columns = header
+				continue
+			}
+		}
+
+		if len(fields) == 1 && strings.TrimSpace(fields[0]) == "" {
+			continue
+		}
+
+		if err := fn(Row{Line: line, Fields: fields, Columns: columns}); err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Message: err.Error()})
+		}
+	}
+
+	if len(rowErrors) > 0 {
+		return rowErrors
+	}
+	return nil
+}
+
+// header returns the positions of the known columns named by a row
+func (f Format) header(fields []string) Columns {
+	normalize := strings.NewReplacer(" ", "_", "-", "_")
+	columns := make(Columns)
+	for i, field := range fields {
+		name := normalize.Replace(strings.ToLower(strings.TrimSpace(field)))
+		for _, known := range f.Columns {
+			if name == known {
+				columns[name] = i
+			}
+		}
+	}
+	return columns
+}
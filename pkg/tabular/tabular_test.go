@@ -0,0 +1,79 @@
+package tabular
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testFormat = Format{
+	Comma:    ',',
+	Columns:  []string{"date", "first_name"},
+	Defaults: Columns{"date": 0, "first_name": 1},
+	IsHeader: func(columns Columns) bool {
+		_, ok := columns["date"]
+		return ok
+	},
+}
+
+// readTestRows reads input, rejecting rows without a first name
+func readTestRows(t *testing.T, input string, format Format) ([]Row, error) {
+	var rows []Row
+	err := Read(strings.NewReader(input), format, func(row Row) error {
+		if row.Field("first_name") == "" {
+			return errors.New("empty name")
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	return rows, err
+}
+
+func TestReadWithHeaderAndBOM(t *testing.T) {
+	rows, err := readTestRows(t, "\ufeffFirst Name, Date\n Anna ,07-26\n\n  \nJānis,06-24\n", testFormat)
+	if err != nil {
+		t.Fatalf("Read returned an error: %v", err)
+	}
+
+	if len(rows) != 2 || rows[0].Line != 2 || rows[1].Line != 5 {
+		t.Fatalf("Unexpected rows: %+v", rows)
+	}
+	if rows[0].Field("first_name") != "Anna" || rows[0].Field("date") != "07-26" || rows[0].Field("email") != "" {
+		t.Errorf("Unexpected fields: %+v", rows[0])
+	}
+}
+
+func TestReadWithoutHeader(t *testing.T) {
+	format := testFormat
+	format.Comma = '\t'
+	rows, err := readTestRows(t, "06-24\tJā\"nis\n07-26\tAnna\textra\n", format)
+	if err != nil {
+		t.Fatalf("Read returned an error: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Field("first_name") != `Jā"nis` || rows[1].Field("first_name") != "Anna" {
+		t.Errorf("Unexpected rows: %+v", rows)
+	}
+}
+
+func TestReadRowErrors(t *testing.T) {
+	rows, err := readTestRows(t, "date,first_name\n06-24,Jānis\n06-24,\n07-26,\"Anna\n", testFormat)
+
+	var rowErrors RowErrors
+	if !errors.As(err, &rowErrors) {
+		t.Fatalf("Expected RowErrors, got %v", err)
+	}
+	lines := []int{}
+	for _, e := range rowErrors {
+		lines = append(lines, e.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{3, 4}) {
+		t.Errorf("Unexpected error lines: %v (%v)", lines, err)
+	}
+	if len(rows) != 1 {
+		t.Errorf("Expected the valid row to be kept, got %+v", rows)
+	}
+	if err.Error() != "line 3: empty name\n"+rowErrors[1].Error() {
+		t.Errorf("Unexpected error message: %q", err.Error())
+	}
+}
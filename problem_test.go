@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func readTestProblem(t *testing.T, rr *httptest.ResponseRecorder) Problem {
+	if contentType := rr.Header().Get("Content-Type"); contentType != ProblemContentType {
+		t.Errorf("Expected Content-Type %s, got %q", ProblemContentType, contentType)
+	}
+
+	var problem Problem
+	if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to unmarshal problem: %v", err)
+	}
+	if problem.Status != rr.Code {
+		t.Errorf("Expected problem status %d, got %d", rr.Code, problem.Status)
+	}
+	return problem
+}
+
+func TestWriteProblem(t *testing.T) {
+	rr, req := setupTestRequest(t, http.MethodGet, "/nameday/john-smith", nil)
+	writeProblem(rr, req, newProblem(http.StatusNotFound, "no nameday for john-smith"))
+
+	checkResponseStatus(t, rr, http.StatusNotFound)
+	problem := readTestProblem(t, rr)
+	expected := Problem{Type: "about:blank", Title: "Not Found", Status: http.StatusNotFound, Detail: "no nameday for john-smith", Instance: "/nameday/john-smith"}
+	if !reflect.DeepEqual(problem, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, problem)
+	}
+}
+
+func TestNamedayHandlerProblems(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"malformed JSON", http.MethodPost, "/nameday", "", `{"name": "Anna",`, http.StatusBadRequest},
+		{"empty body", http.MethodPost, "/nameday", "application/json", "", http.StatusBadRequest},
+		{"trailing data", http.MethodPost, "/nameday", "", `{"name": "Anna", "date": "07-26"} {}`, http.StatusBadRequest},
+		{"wrong content type", http.MethodPost, "/nameday", "text/plain", `{"name": "Anna", "date": "07-26"}`, http.StatusUnsupportedMediaType},
+		{"wrong field type", http.MethodPost, "/nameday", "application/json; charset=utf-8", `{"name": 42, "date": "07-26"}`, http.StatusUnprocessableEntity},
+		{"name without letters", http.MethodPost, "/nameday", "", `{"name": "!!", "date": "07-26"}`, http.StatusUnprocessableEntity},
+		{"existing nameday", http.MethodPost, "/nameday", "", `{"name": "John-Smith", "date": "05-15"}`, http.StatusConflict},
+		{"missing nameday", http.MethodGet, "/nameday/jane-doe", "", "", http.StatusNotFound},
+		{"unknown path", http.MethodGet, "/nameday/John%20Smith/extra", "", "", http.StatusNotFound},
+		{"malformed update", http.MethodPut, johnSmithPath, "", `[]`, http.StatusUnprocessableEntity},
+		{"wrong method", http.MethodPatch, johnSmithPath, "", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, handler := createTestNamedayHandler()
+			addTestNameday(store, johnSmithKey, testJohnSmith, "04-12")
+
+			rr, req := setupTestRequest(t, tt.method, tt.path, []byte(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			handler.ServeHTTP(rr, req)
+
+			checkResponseStatus(t, rr, tt.status)
+			if problem := readTestProblem(t, rr); problem.Title != http.StatusText(tt.status) {
+				t.Errorf("Expected title %q, got %q", http.StatusText(tt.status), problem.Title)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DateRange is the JSON representation of the namedays between two dates,
+// in calendar order starting at From
+type DateRange struct {
+	Country string     `json:"country"`
+	From    string     `json:"from"`
+	To      string     `json:"to"`
+	Days    []MonthDay `json:"days"`
+}
+
+// UpcomingNameday is a day with namedays in the upcoming window
+type UpcomingNameday struct {
+	Date      string   `json:"date"`
+	Year      int      `json:"year"`
+	DaysUntil int      `json:"days_until"`
+	Names     []string `json:"names"`
+}
+
+// Upcoming is the JSON representation of the namedays in the next few days
+type Upcoming struct {
+	Country  string            `json:"country"`
+	From     string            `json:"from"`
+	Days     int               `json:"days"`
+	Namedays []UpcomingNameday `json:"namedays"`
+}
+
+// getNamedaysForRange returns the days between from and to (inclusive) that
+// have namedays. When from is after to the range wraps across the new year,
+// so 12-28..01-04 lists December before January.
+func getNamedaysForRange(db *sql.DB, country, from, to string) ([]MonthDay, error) {
+	var rows *sql.Rows
+	var err error
+	if from <= to {
+		rows, err = db.Query("SELECT date, name FROM namedays WHERE country = ? AND date BETWEEN ? AND ? ORDER BY date, id", country, from, to)
+	} else {
+		rows, err = db.Query("SELECT date, name FROM namedays WHERE country = ? AND (date >= ? OR date <= ?) ORDER BY date < ?, date, id", country, from, to, from)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	days := []MonthDay{}
+	for rows.Next() {
+		var date, name string
+		if err := rows.Scan(&date, &name); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, MonthDay{Date: date})
+		}
+		days[len(days)-1].Names = append(days[len(days)-1].Names, name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return days, nil
+}
+
+// getUpcomingNamedays returns the namedays in the n days starting at from.
+// 02-29 is only listed when the window falls in a leap year.
+func getUpcomingNamedays(db *sql.DB, country string, from time.Time, n int) ([]UpcomingNameday, error) {
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, n-1)
+
+	first, last := start.Format("01-02"), end.Format("01-02")
+	if n >= 365 {
+		// The window covers every day of the calendar
+		first, last = "01-01", "12-31"
+	}
+
+	days, err := getNamedaysForRange(db, country, first, last)
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string][]string, len(days))
+	for _, day := range days {
+		byDate[day.Date] = day.Names
+	}
+
+	upcoming := []UpcomingNameday{}
+	for i := 0; i < n; i++ {
+		current := start.AddDate(0, 0, i)
+		if names, ok := byDate[current.Format("01-02")]; ok {
+			upcoming = append(upcoming, UpcomingNameday{
+				Date:      current.Format("01-02"),
+				Year:      current.Year(),
+				DaysUntil: i,
+				Names:     names,
+			})
+		}
+	}
+	return upcoming, nil
+}
+
+type rangeHandler struct {
+	db *sql.DB
+}
+
+func NewRangeHandler(db *sql.DB) *rangeHandler {
+	return &rangeHandler{db: db}
+}
+
+func (h *rangeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	from, err := ParseMonthDay(r.URL.Query().Get("from"))
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	to, err := ParseMonthDay(r.URL.Query().Get("to"))
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	days, err := getNamedaysForRange(h.db, country, from, to)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, DateRange{Country: country, From: from, To: to, Days: days})
+}
+
+type upcomingHandler struct {
+	db    *sql.DB
+	clock Clock
+}
+
+func NewUpcomingHandler(db *sql.DB) *upcomingHandler {
+	return &upcomingHandler{db: db, clock: systemClock{}}
+}
+
+func (h *upcomingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	now, err := requestNow(h.clock, r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	from, err := parseReferenceDate(r.URL.Query().Get("date"), now)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	days, err := intParam(r.URL.Query().Get("days"), 7, 1, 366)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	namedays, err := getUpcomingNamedays(h.db, country, from, days)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, Upcoming{
+		Country:  country,
+		From:     from.Format("2006-01-02"),
+		Days:     days,
+		Namedays: namedays,
+	})
+}
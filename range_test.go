@@ -0,0 +1,173 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func createTestRangeDb(t *testing.T) *sql.DB {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "12-28", "Inga", "Ivonna")
+	insertTestNames(t, db, "12-31", "Silvestrs")
+	insertTestNames(t, db, "01-01", "Laimnesis")
+	insertTestNames(t, db, "01-04", "Ilva")
+	insertTestNames(t, db, "01-05", "Sīmanis")
+	insertTestNames(t, db, "02-29", "Kasjans")
+	insertTestNames(t, db, "06-24", "Jānis")
+	return db
+}
+
+func rangeDates(days []MonthDay) []string {
+	dates := []string{}
+	for _, day := range days {
+		dates = append(dates, day.Date)
+	}
+	return dates
+}
+
+func TestGetNamedaysForRangeWrapsYear(t *testing.T) {
+	db := createTestRangeDb(t)
+
+	days, err := getNamedaysForRange(db, "lv", "12-28", "01-04")
+	if err != nil {
+		t.Fatalf("getNamedaysForRange returned an error: %v", err)
+	}
+
+	expected := []string{"12-28", "12-31", "01-01", "01-04"}
+	if dates := rangeDates(days); len(dates) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, dates)
+	} else {
+		for i := range expected {
+			if dates[i] != expected[i] {
+				t.Errorf("Expected %v, got %v", expected, dates)
+				break
+			}
+		}
+	}
+	if len(days[0].Names) != 2 || days[0].Names[0] != "Inga" || days[0].Names[1] != "Ivonna" {
+		t.Errorf("Unexpected names for 12-28: %v", days[0].Names)
+	}
+}
+
+func TestGetNamedaysForRange(t *testing.T) {
+	db := createTestRangeDb(t)
+
+	days, err := getNamedaysForRange(db, "lv", "01-02", "06-24")
+	if err != nil {
+		t.Fatalf("getNamedaysForRange returned an error: %v", err)
+	}
+	if dates := rangeDates(days); len(dates) != 4 || dates[0] != "01-04" || dates[3] != "06-24" {
+		t.Errorf("Unexpected dates: %v", dates)
+	}
+
+	days, err = getNamedaysForRange(db, "lv", "06-24", "06-24")
+	if err != nil {
+		t.Fatalf("getNamedaysForRange returned an error: %v", err)
+	}
+	if len(days) != 1 || days[0].Names[0] != "Jānis" {
+		t.Errorf("Expected a single day range to return Jānis, got %v", days)
+	}
+}
+
+func TestRangeHandler(t *testing.T) {
+	db := createTestRangeDb(t)
+	handler := NewRangeHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/range?from=12-30&to=01-01", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	expected := `{"country":"lv","from":"12-30","to":"01-01","days":[{"date":"12-31","names":["Silvestrs"]},{"date":"01-01","names":["Laimnesis"]}]}`
+	if body := rr.Body.String(); body != expected {
+		t.Errorf("Unexpected body: %s", body)
+	}
+
+	for _, path := range []string{"/api/v1/range?from=12-30", "/api/v1/range?from=13-40&to=01-01", "/api/v1/range?from=01-01&to=02-30"} {
+		rr, req = setupTestRequest(t, http.MethodGet, path, nil)
+		handler.ServeHTTP(rr, req)
+		checkResponseStatus(t, rr, http.StatusBadRequest)
+	}
+}
+
+func TestUpcomingHandler(t *testing.T) {
+	db := createTestRangeDb(t)
+	handler := NewUpcomingHandler(db)
+	handler.clock = fixedClock(time.Date(2023, time.December, 28, 10, 0, 0, 0, time.UTC))
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/upcoming", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	var result Upcoming
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if result.From != "2023-12-28" || result.Days != 7 {
+		t.Errorf("Unexpected window: %s + %d days", result.From, result.Days)
+	}
+
+	expected := []UpcomingNameday{
+		{Date: "12-28", Year: 2023, DaysUntil: 0},
+		{Date: "12-31", Year: 2023, DaysUntil: 3},
+		{Date: "01-01", Year: 2024, DaysUntil: 4},
+	}
+	if len(result.Namedays) != len(expected) {
+		t.Fatalf("Expected %d days, got %v", len(expected), result.Namedays)
+	}
+	for i, e := range expected {
+		got := result.Namedays[i]
+		if got.Date != e.Date || got.Year != e.Year || got.DaysUntil != e.DaysUntil {
+			t.Errorf("Expected %v, got %v", e, got)
+		}
+	}
+}
+
+func TestUpcomingHandlerLeapDay(t *testing.T) {
+	db := createTestRangeDb(t)
+	handler := NewUpcomingHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/upcoming?date=2023-02-27&days=3", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	if body := rr.Body.String(); body != `{"country":"lv","from":"2023-02-27","days":3,"namedays":[]}` {
+		t.Errorf("Did not expect 02-29 in a common year, got %s", body)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/api/v1/upcoming?date=2024-02-27&days=3", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	if body := rr.Body.String(); body != `{"country":"lv","from":"2024-02-27","days":3,"namedays":[{"date":"02-29","year":2024,"days_until":2,"names":["Kasjans"]}]}` {
+		t.Errorf("Expected 02-29 in a leap year, got %s", body)
+	}
+}
+
+func TestUpcomingHandlerWholeYear(t *testing.T) {
+	db := createTestRangeDb(t)
+	handler := NewUpcomingHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/upcoming?date=2024-06-25&days=366", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	var result Upcoming
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(result.Namedays) != 6 || result.Namedays[0].Date != "12-28" || result.Namedays[5].Date != "06-24" {
+		t.Errorf("Unexpected namedays for a whole year: %v", result.Namedays)
+	}
+}
+
+func TestUpcomingHandlerInvalidDays(t *testing.T) {
+	db := createTestRangeDb(t)
+	handler := NewUpcomingHandler(db)
+
+	for _, days := range []string{"0", "367", "week"} {
+		rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/upcoming?days="+days, nil)
+		handler.ServeHTTP(rr, req)
+		checkResponseStatus(t, rr, http.StatusBadRequest)
+	}
+}
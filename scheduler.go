@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultWebhookTime        = "08:00"
+	DefaultWebhookTimeout     = 10 * time.Second
+	DefaultWebhookMaxAttempts = 4
+	DefaultWebhookBackoff     = 2 * time.Second
+
+	WebhookEvent           = "namedays.today"
+	WebhookSignatureHeader = "X-Namedays-Signature"
+)
+
+// WebhookPayload is the JSON body POSTed to webhooks
+type WebhookPayload struct {
+	Event    string         `json:"event"`
+	Country  string         `json:"country"`
+	Day      string         `json:"day"`
+	Date     string         `json:"date"`
+	Names    []string       `json:"names"`
+	List     string         `json:"list,omitempty"`
+	Contacts []ContactMatch `json:"contacts,omitempty"`
+}
+
+// SignWebhook returns the signature header value for a payload, the hex
+// HMAC-SHA256 of the body prefixed with "sha256="
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseSchedule parses a local time of day such as "08:00"
+func parseSchedule(s string) (dailySchedule, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return dailySchedule{}, fmt.Errorf("invalid time of day %q: expected HH:MM", s)
+	}
+	return dailySchedule{hour: t.Hour(), minute: t.Minute()}, nil
+}
+
+// webhookScheduler delivers the day's namedays to every webhook once a day
+// at a fixed local time, retrying failed deliveries with exponential backoff.
+// allowPrivate lets webhooks reach loopback, private and link-local
+// addresses, for receivers on the same host or network.
+type webhookScheduler struct {
+	db           *sql.DB
+	client       *http.Client
+	clock        Clock
+	schedule     dailySchedule
+	maxAttempts  int
+	backoff      time.Duration
+	allowPrivate bool
+}
+
+func NewWebhookScheduler(db *sql.DB, at string, allowPrivate bool) (*webhookScheduler, error) {
+	schedule, err := parseSchedule(at)
+	if err != nil {
+		return nil, err
+	}
+	return &webhookScheduler{
+		db:           db,
+		client:       newWebhookClient(allowPrivate),
+		clock:        systemClock{},
+		schedule:     schedule,
+		maxAttempts:  DefaultWebhookMaxAttempts,
+		backoff:      DefaultWebhookBackoff,
+		allowPrivate: allowPrivate,
+	}, nil
+}
+
+// newWebhookClient returns an HTTP client that only connects to public
+// addresses, unless allowPrivate. The check runs on the resolved address at
+// dial time, so a webhook host that resolves, or redirects, to an internal
+// service is refused as well.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: DefaultWebhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || (!allowPrivate && internalIP(ip)) {
+				return fmt.Errorf("refusing to connect to %s: not a public address", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: DefaultWebhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: DefaultWebhookTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which
+// net.IP has no predicate for
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// internalIP reports whether ip belongs to this host or a private network
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// dailySchedule is a local time of day at which a job runs
+type dailySchedule struct {
+	hour   int
+	minute int
+}
+
+// scheduledAt returns the run time on the day of now
+func (d dailySchedule) scheduledAt(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), d.hour, d.minute, 0, 0, now.Location())
+}
+
+// nextRun returns the first run time after now
+func (d dailySchedule) nextRun(now time.Time) time.Time {
+	next := d.scheduledAt(now)
+	if !next.After(now) {
+		next = d.scheduledAt(time.Date(now.Year(), now.Month(), now.Day()+1, 12, 0, 0, 0, now.Location()))
+	}
+	return next
+}
+
+// run calls job at the scheduled local time every day until ctx is
+// cancelled. When started after today's run time it catches up straight
+// away, so jobs must skip work that was already done.
+func (d dailySchedule) run(ctx context.Context, clock Clock, job func(ctx context.Context, now time.Time)) {
+	now := clock.Now().In(defaultLocation)
+	if !now.Before(d.scheduledAt(now)) {
+		job(ctx, now)
+	}
+
+	for {
+		now = clock.Now().In(defaultLocation)
+		timer := time.NewTimer(d.nextRun(now).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		job(ctx, clock.Now().In(defaultLocation))
+	}
+}
+
+// buildWebhookPayload collects the namedays of the day of now. With a
+// contact list it also lists the contacts celebrating that day.
+func buildWebhookPayload(db *sql.DB, country, list string, now time.Time) (*WebhookPayload, error) {
+	date := now.Format("01-02")
+	names, err := getNamedaysForDate(db, country, date)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &WebhookPayload{
+		Event:   WebhookEvent,
+		Country: country,
+		Day:     now.Format("2006-01-02"),
+		Date:    date,
+		Names:   names,
+		List:    list,
+	}
+	if list == "" {
+		return payload, nil
+	}
+
+	matches, err := matchContacts(db, country, list)
+	if err != nil {
+		return nil, err
+	}
+	for _, contact := range upcomingContacts(matches, now, 1) {
+		payload.Contacts = append(payload.Contacts, contact.ContactMatch)
+	}
+	return payload, nil
+}
+
+// Deliver POSTs the namedays of the day of now to a webhook in its message
+// format, logging every attempt. It reports whether anything was sent.
+func (s *webhookScheduler) Deliver(ctx context.Context, hook Webhook, now time.Time) (bool, error) {
+	return s.deliver(ctx, hook, now, s.maxAttempts)
+}
+
+// DeliverOnce is Deliver without retries, for callers that wait on the
+// result
+func (s *webhookScheduler) DeliverOnce(ctx context.Context, hook Webhook, now time.Time) (bool, error) {
+	return s.deliver(ctx, hook, now, 1)
+}
+
+func (s *webhookScheduler) deliver(ctx context.Context, hook Webhook, now time.Time, maxAttempts int) (bool, error) {
+	payload, err := buildWebhookPayload(s.db, hook.Country, hook.List, now)
+	if err != nil {
+		return false, err
+	}
+	if hook.List != "" && len(payload.Contacts) == 0 {
+		// Nobody on the contact list celebrates today
+		return false, nil
+	}
+
+	message, err := RenderMessage(hook.Format, payload)
+	if err != nil {
+		return false, err
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return false, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		delivery := WebhookDelivery{
+			WebhookID: hook.ID,
+			Day:       payload.Day,
+			Attempt:   attempt,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		retry, err := s.post(ctx, hook, body, &delivery)
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if logErr := logWebhookDelivery(s.db, delivery); logErr != nil {
+			return true, logErr
+		}
+
+		if delivery.Success {
+			return true, nil
+		}
+		if !retry || attempt >= maxAttempts {
+			return true, fmt.Errorf("giving up after %d attempts: %s", attempt, delivery.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case <-time.After(s.backoff << (attempt - 1)):
+		}
+	}
+}
+
+// post makes a single delivery attempt and reports whether a failure is
+// worth retrying
+func (s *webhookScheduler) post(ctx context.Context, hook Webhook, body []byte, delivery *WebhookDelivery) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "namedays-webhook/1")
+	req.Header.Set("X-Namedays-Event", WebhookEvent)
+	req.Header.Set("X-Namedays-Delivery", fmt.Sprintf("%d-%s", hook.ID, delivery.Day))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(hook.Secret, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		delivery.Success = true
+		return false, nil
+	}
+
+	// Client errors will not go away by retrying, except rate limiting
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// RunOnce delivers the namedays of the day of now to every webhook that
+// has not received them yet
+func (s *webhookScheduler) RunOnce(ctx context.Context, now time.Time) {
+	hooks, err := listWebhooks(s.db)
+	if err != nil {
+		log.Printf("Webhook delivery failed: %v", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		done, err := deliveredOn(s.db, hook.ID, now.Format("2006-01-02"))
+		if err != nil {
+			log.Printf("Webhook %d delivery failed: %v", hook.ID, err)
+			continue
+		}
+		if done {
+			continue
+		}
+		if _, err = s.Deliver(ctx, hook, now); err != nil {
+			log.Printf("Webhook %d delivery failed: %v", hook.ID, err)
+		}
+	}
+}
+
+// Run delivers at the configured local time every day until ctx is
+// cancelled
+func (s *webhookScheduler) Run(ctx context.Context) {
+	s.schedule.run(ctx, s.clock, s.RunOnce)
+}
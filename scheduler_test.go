@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testReceiver is a webhook endpoint answering with the queued status
+// codes, then 200
+type testReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rcv *testReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.requests = append(rcv.requests, r)
+	rcv.bodies = append(rcv.bodies, body)
+
+	status := http.StatusOK
+	if len(rcv.statuses) > 0 {
+		status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func createTestScheduler(t *testing.T) (*webhookScheduler, *testReceiver, string) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "06-24", "Jānis")
+
+	scheduler, err := NewWebhookScheduler(db, "08:00", false)
+	if err != nil {
+		t.Fatalf("NewWebhookScheduler returned an error: %v", err)
+	}
+	scheduler.backoff = time.Millisecond
+
+	receiver := &testReceiver{}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+	// The receiver listens on loopback, which the webhook client refuses
+	scheduler.client = server.Client()
+	return scheduler, receiver, server.URL
+}
+
+func addTestWebhook(t *testing.T, s *webhookScheduler, hook Webhook) Webhook {
+	// Validate with a public host, the test receiver is on loopback
+	url := hook.URL
+	hook.URL = "https://receiver.example.com/hook"
+	if err := validateWebhook(&hook, false); err != nil {
+		t.Fatalf("validateWebhook returned an error: %v", err)
+	}
+	hook.URL = url
+	if err := createWebhook(s.db, &hook); err != nil {
+		t.Fatalf("createWebhook returned an error: %v", err)
+	}
+	return hook
+}
+
+var midsummer = time.Date(2024, time.June, 24, 8, 0, 0, 0, time.UTC)
+
+func TestWebhookDeliverySignsPayload(t *testing.T) {
+	scheduler, receiver, url := createTestScheduler(t)
+	hook := addTestWebhook(t, scheduler, Webhook{URL: url, Secret: "s3cret"})
+
+	sent, err := scheduler.Deliver(context.Background(), hook, midsummer)
+	if err != nil || !sent {
+		t.Fatalf("Deliver returned %v, %v", sent, err)
+	}
+
+	if len(receiver.requests) != 1 {
+		t.Fatalf("Expected one request, got %d", len(receiver.requests))
+	}
+	req, body := receiver.requests[0], receiver.bodies[0]
+	if signature := req.Header.Get(WebhookSignatureHeader); signature != SignWebhook("s3cret", body) {
+		t.Errorf("Unexpected signature %q", signature)
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got %s", ct)
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Failed to unmarshal payload: %v", err)
+	}
+	if payload.Day != "2024-06-24" || payload.Date != "06-24" || len(payload.Names) != 1 || payload.Names[0] != "Jānis" {
+		t.Errorf("Unexpected payload: %s", body)
+	}
+}
+
+func TestWebhookDeliveryRetries(t *testing.T) {
+	scheduler, receiver, url := createTestScheduler(t)
+	receiver.statuses = []int{http.StatusBadGateway, http.StatusTooManyRequests}
+	hook := addTestWebhook(t, scheduler, Webhook{URL: url})
+
+	if _, err := scheduler.Deliver(context.Background(), hook, midsummer); err != nil {
+		t.Fatalf("Deliver returned an error: %v", err)
+	}
+
+	deliveries, err := listWebhookDeliveries(scheduler.db, hook.ID)
+	if err != nil {
+		t.Fatalf("listWebhookDeliveries returned an error: %v", err)
+	}
+	if len(deliveries) != 3 {
+		t.Fatalf("Expected 3 logged attempts, got %v", deliveries)
+	}
+	if latest := deliveries[0]; latest.Attempt != 3 || !latest.Success || latest.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected final attempt: %v", latest)
+	}
+	if first := deliveries[2]; first.Success || first.StatusCode != http.StatusBadGateway || first.Error == "" {
+		t.Errorf("Unexpected first attempt: %v", first)
+	}
+}
+
+func TestWebhookDeliveryGivesUp(t *testing.T) {
+	scheduler, receiver, url := createTestScheduler(t)
+	receiver.statuses = []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}
+	hook := addTestWebhook(t, scheduler, Webhook{URL: url})
+
+	if _, err := scheduler.Deliver(context.Background(), hook, midsummer); err == nil {
+		t.Error("Expected an error after exhausting retries")
+	}
+	if len(receiver.requests) != DefaultWebhookMaxAttempts {
+		t.Errorf("Expected %d attempts, got %d", DefaultWebhookMaxAttempts, len(receiver.requests))
+	}
+
+	// Client errors are not retried
+	receiver.statuses = []int{http.StatusGone}
+	receiver.requests = nil
+	if _, err := scheduler.Deliver(context.Background(), hook, midsummer); err == nil {
+		t.Error("Expected an error for a gone receiver")
+	}
+	if len(receiver.requests) != 1 {
+		t.Errorf("Expected a single attempt, got %d", len(receiver.requests))
+	}
+}
+
+func TestWebhookDeliveryContactList(t *testing.T) {
+	scheduler, receiver, url := createTestScheduler(t)
+	hook := addTestWebhook(t, scheduler, Webhook{URL: url, List: "team"})
+
+	// Nobody on the list celebrates, so nothing is sent
+	sent, err := scheduler.Deliver(context.Background(), hook, midsummer)
+	if err != nil || sent {
+		t.Fatalf("Expected nothing to be sent, got %v, %v", sent, err)
+	}
+
+	uploadTestContacts(t, NewContactsHandler(scheduler.db), "/api/v1/contacts?list=team", "text/csv", "name\nJanis Bērziņš\nAnna Ozola\n")
+	if sent, err = scheduler.Deliver(context.Background(), hook, midsummer); err != nil || !sent {
+		t.Fatalf("Deliver returned %v, %v", sent, err)
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(receiver.bodies[0], &payload); err != nil {
+		t.Fatalf("Failed to unmarshal payload: %v", err)
+	}
+	if payload.List != "team" || len(payload.Contacts) != 1 || payload.Contacts[0].Name != "Janis Bērziņš" {
+		t.Errorf("Expected only Janis in the payload, got %s", receiver.bodies[0])
+	}
+}
+
+func TestWebhookRunOnceDeliversOncePerDay(t *testing.T) {
+	addTestCountries(t)
+	scheduler, receiver, url := createTestScheduler(t)
+	addTestWebhook(t, scheduler, Webhook{URL: url})
+	addTestWebhook(t, scheduler, Webhook{URL: url, Country: "lt"})
+
+	scheduler.RunOnce(context.Background(), midsummer)
+	scheduler.RunOnce(context.Background(), midsummer.Add(time.Hour))
+	if len(receiver.requests) != 2 {
+		t.Errorf("Expected one delivery per webhook, got %d", len(receiver.requests))
+	}
+
+	scheduler.RunOnce(context.Background(), midsummer.AddDate(0, 0, 1))
+	if len(receiver.requests) != 4 {
+		t.Errorf("Expected another delivery the next day, got %d", len(receiver.requests))
+	}
+}
+
+func TestWebhookSchedulerNextRun(t *testing.T) {
+	scheduler, err := NewWebhookScheduler(nil, "08:30", false)
+	if err != nil {
+		t.Fatalf("NewWebhookScheduler returned an error: %v", err)
+	}
+
+	riga, _ := time.LoadLocation("Europe/Riga")
+	cases := []struct {
+		now, expected time.Time
+	}{
+		{time.Date(2024, 6, 24, 7, 0, 0, 0, riga), time.Date(2024, 6, 24, 8, 30, 0, 0, riga)},
+		{time.Date(2024, 6, 24, 8, 30, 0, 0, riga), time.Date(2024, 6, 25, 8, 30, 0, 0, riga)},
+		{time.Date(2024, 12, 31, 23, 0, 0, 0, riga), time.Date(2025, 1, 1, 8, 30, 0, 0, riga)},
+		// The night the clocks go forward
+		{time.Date(2024, 3, 30, 9, 0, 0, 0, riga), time.Date(2024, 3, 31, 8, 30, 0, 0, riga)},
+	}
+	for _, c := range cases {
+		if next := scheduler.schedule.nextRun(c.now); !next.Equal(c.expected) {
+			t.Errorf("nextRun(%s) returned %s, expected %s", c.now, next, c.expected)
+		}
+	}
+
+	if _, err = NewWebhookScheduler(nil, "8am", false); err == nil {
+		t.Error("Expected an error for an invalid time of day")
+	}
+}
+
+func TestWebhookHandlerDeliver(t *testing.T) {
+	scheduler, receiver, url := createTestScheduler(t)
+	scheduler.clock = fixedClock(midsummer)
+	hook := addTestWebhook(t, scheduler, Webhook{URL: url})
+	handler := NewWebhookHandler(scheduler.db, scheduler)
+
+	rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/webhooks/1/deliveries", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	if len(receiver.requests) != 1 {
+		t.Fatalf("Expected a delivery, got %d", len(receiver.requests))
+	}
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/api/v1/webhooks/1/deliveries", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	var deliveries []WebhookDelivery
+	if err := json.Unmarshal(rr.Body.Bytes(), &deliveries); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].WebhookID != hook.ID || deliveries[0].Day != "2024-06-24" || !deliveries[0].Success {
+		t.Errorf("Unexpected delivery log: %v", deliveries)
+	}
+}
+
+func TestWebhookHandlerDeliverMakesOneAttempt(t *testing.T) {
+	scheduler, receiver, url := createTestScheduler(t)
+	scheduler.clock = fixedClock(midsummer)
+	receiver.statuses = []int{http.StatusBadGateway, http.StatusBadGateway}
+	addTestWebhook(t, scheduler, Webhook{URL: url})
+	handler := NewWebhookHandler(scheduler.db, scheduler)
+
+	rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/webhooks/1/deliveries", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+
+	var deliveries []WebhookDelivery
+	if err := json.Unmarshal(rr.Body.Bytes(), &deliveries); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(receiver.requests) != 1 || len(deliveries) != 1 || deliveries[0].Success {
+		t.Errorf("Expected a single failed attempt, got %d requests and %v", len(receiver.requests), deliveries)
+	}
+}
+
+func TestWebhookClientRefusesInternalAddresses(t *testing.T) {
+	scheduler, receiver, url := createTestScheduler(t)
+	scheduler.client = newWebhookClient(false)
+	scheduler.maxAttempts = 1
+	hook := addTestWebhook(t, scheduler, Webhook{URL: url})
+
+	_, err := scheduler.Deliver(context.Background(), hook, midsummer)
+	if err == nil || !strings.Contains(err.Error(), "not a public address") {
+		t.Errorf("Expected the loopback receiver to be refused, got %v", err)
+	}
+	if len(receiver.requests) != 0 {
+		t.Errorf("Expected no request to reach the receiver, got %d", len(receiver.requests))
+	}
+}
+
+func TestWebhookClientAllowPrivate(t *testing.T) {
+	scheduler, receiver, url := createTestScheduler(t)
+	scheduler.client = newWebhookClient(true)
+	scheduler.maxAttempts = 1
+	hook := addTestWebhook(t, scheduler, Webhook{URL: url})
+
+	if ok, err := scheduler.Deliver(context.Background(), hook, midsummer); !ok || err != nil {
+		t.Errorf("Expected delivery to the loopback receiver, got %v, %v", ok, err)
+	}
+	if len(receiver.requests) != 1 {
+		t.Errorf("Expected a request to reach the receiver, got %d", len(receiver.requests))
+	}
+}
+
+func TestInternalIP(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::ffff:127.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		if internal := internalIP(net.ParseIP(tt.ip)); internal != tt.expected {
+			t.Errorf("internalIP(%s) = %v, want %v", tt.ip, internal, tt.expected)
+		}
+	}
+}
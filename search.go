@@ -0,0 +1,306 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gosimple/unidecode"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchDistance  = 3
+)
+
+// Match kinds, ordered from best to worst
+const (
+	MatchExact  = "exact"
+	MatchPrefix = "prefix"
+	MatchFuzzy  = "fuzzy"
+)
+
+var matchRank = map[string]int{MatchExact: 0, MatchPrefix: 1, MatchFuzzy: 2}
+
+// SearchResult is a single name matched by a search query. Spellings that
+// normalize to the same name, such as Aristida and Aristīda, are a single
+// result listing the others as variants.
+type SearchResult struct {
+	Name     string   `json:"name"`
+	Variants []string `json:"variants,omitempty"`
+	Dates    []string `json:"dates"`
+	Match    string   `json:"match"`
+	Distance int      `json:"distance"`
+}
+
+type searchEntry struct {
+	names []string
+	key   string
+	dates []string
+}
+
+// SearchIndex holds every name grouped by its normalized spelling, so that
+// "Aristida" and "Aristīda" are compared as equals
+type SearchIndex struct {
+	entries []*searchEntry
+}
+
+// NormalizeName lowercases a name and transliterates it to ASCII
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(unidecode.Unidecode(name)))
+}
+
+// LoadSearchIndex builds a SearchIndex from a country's namedays
+func LoadSearchIndex(db *sql.DB, country string) (*SearchIndex, error) {
+	rows, err := db.Query("SELECT name, date FROM namedays WHERE country = ? ORDER BY date, id", country)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	index := &SearchIndex{}
+	byKey := make(map[string]*searchEntry)
+	for rows.Next() {
+		var name, date string
+		if err := rows.Scan(&name, &date); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+
+		key := NormalizeName(name)
+		entry, ok := byKey[key]
+		if !ok {
+			entry = &searchEntry{key: key}
+			byKey[key] = entry
+			index.entries = append(index.entries, entry)
+		}
+		if !containsString(entry.names, name) {
+			entry.names = append(entry.names, name)
+		}
+		// Rows come ordered by date, so a repeated date is the last one
+		if n := len(entry.dates); n == 0 || entry.dates[n-1] != date {
+			entry.dates = append(entry.dates, date)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return index, nil
+}
+
+// Search returns names that equal, start with, or are within maxDistance
+// edits of the query, best matches first
+func (idx *SearchIndex) Search(query string, maxDistance, limit int) []SearchResult {
+	q := NormalizeName(query)
+	results := []SearchResult{}
+	if q == "" {
+		return results
+	}
+
+	for _, e := range idx.entries {
+		result := SearchResult{Name: e.names[0], Variants: e.names[1:], Dates: e.dates}
+		switch {
+		case e.key == q:
+			result.Match = MatchExact
+		case strings.HasPrefix(e.key, q):
+			result.Match = MatchPrefix
+			result.Distance = len([]rune(e.key)) - len([]rune(q))
+		default:
+			d := levenshtein(q, e.key, maxDistance)
+			if d > maxDistance {
+				continue
+			}
+			result.Match = MatchFuzzy
+			result.Distance = d
+		}
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if matchRank[a.Match] != matchRank[b.Match] {
+			return matchRank[a.Match] < matchRank[b.Match]
+		}
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		return a.Name < b.Name
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// defaultDistance allows fewer typos in short queries, where a couple of
+// edits would match almost anything
+func defaultDistance(query string) int {
+	if len([]rune(NormalizeName(query))) <= 4 {
+		return 1
+	}
+	return 2
+}
+
+// levenshtein computes the edit distance between a and b, giving up early
+// and returning max+1 once the distance is known to exceed max
+func levenshtein(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > max || -diff > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// searchGeneration is bumped whenever namedays change, so that search
+// handlers know their cached indexes are stale
+var searchGeneration uint64
+
+// invalidateSearchIndexes makes every search handler rebuild its indexes
+// on the next request. Changes made by another process, such as db-ops,
+// are only picked up after a restart.
+func invalidateSearchIndexes() {
+	atomic.AddUint64(&searchGeneration, 1)
+}
+
+type cachedSearchIndex struct {
+	index      *SearchIndex
+	generation uint64
+}
+
+type searchHandler struct {
+	db      *sql.DB
+	mu      sync.Mutex
+	indexes map[string]cachedSearchIndex
+}
+
+func NewSearchHandler(db *sql.DB) *searchHandler {
+	return &searchHandler{db: db, indexes: make(map[string]cachedSearchIndex)}
+}
+
+// index returns the search index of a country, building it on first use
+// and again after namedays change
+func (h *searchHandler) index(country string) (*SearchIndex, error) {
+	// Read the generation before the table, so a change made while the
+	// index is built invalidates it
+	generation := atomic.LoadUint64(&searchGeneration)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cached, ok := h.indexes[country]; ok && cached.generation == generation {
+		return cached.index, nil
+	}
+
+	index, err := LoadSearchIndex(h.db, country)
+	if err != nil {
+		return nil, err
+	}
+	h.indexes[country] = cachedSearchIndex{index: index, generation: generation}
+	return index, nil
+}
+
+func (h *searchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	limit, err := intParam(query.Get("limit"), defaultSearchLimit, 1, maxSearchLimit)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+	maxDistance, err := intParam(query.Get("max_distance"), defaultDistance(q), 0, maxSearchDistance)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	country, err := requestCountry(r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	index, err := h.index(country)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, index.Search(q, maxDistance, limit))
+}
+
+// intParam parses an optional integer query parameter within [min, max]
+func intParam(s string, def, min, max int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q: %w", s, err)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, min, max)
+	}
+	return v, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func createTestSearchIndex(t *testing.T) *SearchIndex {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "01-21", "Aristida", "Aristīda")
+	insertTestNames(t, db, "01-22", "Aristids", "Aristīds")
+	insertTestNames(t, db, "11-24", "Anna")
+	insertTestNames(t, db, "07-26", "Anna", "Annija")
+
+	index, err := LoadSearchIndex(db, "lv")
+	if err != nil {
+		t.Fatalf("LoadSearchIndex returned an error: %v", err)
+	}
+	return index
+}
+
+func TestNormalizeName(t *testing.T) {
+	cases := map[string]string{
+		"Aristīda": "aristida",
+		"ĪVA":      "iva",
+		" Jānis ":  "janis",
+		"Ģirts":    "girts",
+	}
+	for input, expected := range cases {
+		if result := NormalizeName(input); result != expected {
+			t.Errorf("NormalizeName(%q) returned %s, expected %s", input, result, expected)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"anna", "anna", 0},
+		{"anna", "ana", 1},
+		{"aristida", "aristids", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, c := range cases {
+		if d := levenshtein(c.a, c.b, 5); d != c.expected {
+			t.Errorf("levenshtein(%q, %q) returned %d, expected %d", c.a, c.b, d, c.expected)
+		}
+	}
+
+	// Distances beyond the bound are reported as max+1
+	if d := levenshtein("anna", "aristida", 2); d != 3 {
+		t.Errorf("Expected bounded distance 3, got %d", d)
+	}
+}
+
+func TestSearchIndexExactMatchesIgnoreDiacritics(t *testing.T) {
+	index := createTestSearchIndex(t)
+
+	results := index.Search("aristida", 0, 0)
+	if len(results) != 1 {
+		t.Fatalf("Expected both spellings as a single result, got %v", results)
+	}
+	r := results[0]
+	if r.Name != "Aristida" || !reflect.DeepEqual(r.Variants, []string{"Aristīda"}) || !reflect.DeepEqual(r.Dates, []string{"01-21"}) || r.Match != MatchExact {
+		t.Errorf("Unexpected result: %+v", r)
+	}
+}
+
+func TestSearchIndexRanking(t *testing.T) {
+	index := createTestSearchIndex(t)
+
+	results := index.Search("Aristid", 1, 0)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %v", results)
+	}
+	for _, r := range results {
+		if r.Match != MatchPrefix {
+			t.Errorf("Expected prefix match for %s, got %s", r.Name, r.Match)
+		}
+	}
+
+	results = index.Search("Ana", 1, 0)
+	if len(results) != 1 || results[0].Name != "Anna" || results[0].Match != MatchFuzzy {
+		t.Fatalf("Expected a single fuzzy match for Anna, got %v", results)
+	}
+	if len(results[0].Dates) != 2 {
+		t.Errorf("Expected Anna to be celebrated on 2 dates, got %v", results[0].Dates)
+	}
+
+	results = index.Search("Anna", 2, 0)
+	if len(results) != 2 || results[0].Match != MatchExact || results[1].Name != "Annija" || results[1].Match != MatchFuzzy {
+		t.Errorf("Expected exact match before fuzzy match, got %v", results)
+	}
+}
+
+func TestSearchIndexLimit(t *testing.T) {
+	index := createTestSearchIndex(t)
+
+	if results := index.Search("a", 0, 2); len(results) != 2 {
+		t.Errorf("Expected 2 results, got %d", len(results))
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "01-02", "Īva")
+	handler := NewSearchHandler(db)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/search?q=iva", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+
+	var results []SearchResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "Īva" || results[0].Match != MatchExact {
+		t.Errorf("Unexpected results: %v", results)
+	}
+}
+
+func TestSearchHandlerCachesIndex(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "01-02", "Īva")
+	handler := NewSearchHandler(db)
+
+	search := func() []SearchResult {
+		rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/search?q=ivars", nil)
+		handler.ServeHTTP(rr, req)
+		checkResponseStatus(t, rr, http.StatusOK)
+
+		var results []SearchResult
+		if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return results
+	}
+
+	search()
+	insertTestNames(t, db, "06-25", "Ivars")
+	if results := search(); len(results) != 1 || results[0].Name != "Īva" {
+		t.Errorf("Expected the cached index to be used, got %v", results)
+	}
+
+	invalidateSearchIndexes()
+	if results := search(); len(results) != 2 || results[0].Name != "Ivars" {
+		t.Errorf("Expected the index to be rebuilt, got %v", results)
+	}
+}
+
+func TestSearchHandlerBadRequest(t *testing.T) {
+	_, db := createTestDb(t)
+	handler := NewSearchHandler(db)
+
+	for _, path := range []string{"/api/v1/search", "/api/v1/search?q=anna&limit=0", "/api/v1/search?q=anna&max_distance=9"} {
+		rr, req := setupTestRequest(t, http.MethodGet, path, nil)
+		handler.ServeHTTP(rr, req)
+		checkResponseStatus(t, rr, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/zrks/namedays/pkg/namedays"
+)
+
+func TestSQLStoreSharesHomePageData(t *testing.T) {
+	_, db := createTestDb(t)
+	handler := NewNamedayHandler(namedays.NewSQLStore(db, defaultCountry))
+
+	// Create a nameday through the CRUD API
+	jsonData, _ := json.Marshal(namedays.Nameday{Name: testJohnSmithValid, Date: "06-24"})
+	rr, req := setupTestRequest(t, http.MethodPost, "/nameday", jsonData)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusCreated)
+
+	// The home page query should see it
+	names, err := getNamedaysForDate(db, defaultCountry, "06-24")
+	if err != nil {
+		t.Fatal("getNamedaysForDate returned an error:", err)
+	}
+	if len(names) != 1 || names[0] != testJohnSmithValid {
+		t.Errorf("Expected [%s], got %v", testJohnSmithValid, names)
+	}
+}
+
+func TestSQLStoreKeepsOtherCountries(t *testing.T) {
+	_, db := createTestDb(t)
+	insertTestNames(t, db, "07-26", "Anna")
+	if _, err := db.Exec("INSERT INTO namedays (date, name, slug, country) VALUES ('07-26', 'Ona', 'anna', 'lt')"); err != nil {
+		t.Fatal("Failed to insert test data:", err)
+	}
+	handler := NewNamedayHandler(namedays.NewSQLStore(db, defaultCountry))
+
+	// Deleting the default calendar's Anna must leave Lithuania alone
+	rr, req := setupTestRequest(t, http.MethodDelete, "/nameday/anna", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusNoContent)
+
+	names, err := getNamedaysForDate(db, "lt", "07-26")
+	if err != nil {
+		t.Fatal("getNamedaysForDate returned an error:", err)
+	}
+	if len(names) != 1 || names[0] != "Ona" {
+		t.Errorf("Expected [Ona] in lt, got %v", names)
+	}
+
+	// and the name can be created again in the default calendar
+	rr, req = setupTestRequest(t, http.MethodPost, "/nameday", []byte(`{"name": "Anna", "date": "07-26"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusCreated)
+}
+
+func TestInitDBAddsSlugColumn(t *testing.T) {
+	tmpDB, err := os.CreateTemp("", "test-legacy-*.db")
+	if err != nil {
+		t.Fatal("Failed to create temporary database:", err)
+	}
+	tmpDBPath := tmpDB.Name()
+	tmpDB.Close()
+	t.Cleanup(func() { os.Remove(tmpDBPath) })
+
+	// Create a database with the schema that predates the slug column
+	db, err := sql.Open("sqlite3", tmpDBPath)
+	if err != nil {
+		t.Fatal("Failed to open database:", err)
+	}
+	defer db.Close()
+
+	if _, err = db.Exec(`CREATE TABLE namedays (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT NOT NULL, name TEXT NOT NULL);`); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	if _, err = db.Exec("INSERT INTO namedays (date, name) VALUES (?, ?)", "04-12", testJohnSmith); err != nil {
+		t.Fatal("Failed to insert test data:", err)
+	}
+
+	if err = InitDB(tmpDBPath, nil); err != nil {
+		t.Fatalf("InitDB returned an error: %v", err)
+	}
+
+	retrieved, err := namedays.NewSQLStore(db, defaultCountry).Get("", johnSmithKey)
+	if err != nil {
+		t.Fatalf("Failed to get backfilled nameday: %v", err)
+	}
+	if retrieved.Name != testJohnSmith || retrieved.Date != "04-12" {
+		t.Errorf("Backfilled nameday does not match: got %v", retrieved)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/zrks/namedays/pkg/importer"
+)
+
+// datasetSync periodically re-imports a remote dataset through the same
+// upsert path as the importer
+type datasetSync struct {
+	db       *sql.DB
+	fetcher  *importer.Fetcher
+	url      string
+	country  string
+	prune    bool
+	interval time.Duration
+}
+
+// NewDatasetSync returns a sync of the dataset at url. A non-empty checksum
+// is the expected hex SHA-256 of the dataset, which is not applied when it
+// does not match.
+func NewDatasetSync(db *sql.DB, url, checksum, country string, prune bool, interval time.Duration) *datasetSync {
+	fetcher := importer.NewFetcher()
+	fetcher.SHA256 = checksum
+	return &datasetSync{
+		db:       db,
+		fetcher:  fetcher,
+		url:      url,
+		country:  country,
+		prune:    prune,
+		interval: interval,
+	}
+}
+
+// RunOnce fetches the dataset and applies it. It returns a nil report when
+// the remote dataset has not changed since the last run.
+func (s *datasetSync) RunOnce(ctx context.Context) (*importer.Report, error) {
+	previous := s.fetcher.State
+
+	data, err := s.fetcher.Fetch(ctx, s.url)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	report, err := importer.Import(s.db, data, importer.Options{Country: s.country, Prune: s.prune})
+	if err != nil {
+		// Forget the cache validators so the next run retries the import
+		s.fetcher.State = previous
+		return nil, fmt.Errorf("failed to import %s: %w", s.url, err)
+	}
+	markCountryLoaded(s.country)
+	invalidateSearchIndexes()
+	return report, nil
+}
+
+// Run syncs immediately and then on every interval until ctx is cancelled
+func (s *datasetSync) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := s.RunOnce(ctx)
+		switch {
+		case err != nil:
+			log.Printf("Dataset sync failed: %v", err)
+		case report != nil:
+			log.Printf("Dataset synced from %s: %d added, %d removed, %d unchanged", s.url, report.Added, report.Removed, report.Unchanged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zrks/namedays/pkg/importer"
+)
+
+func TestDatasetSyncRunOnce(t *testing.T) {
+	_, db := createTestDb(t)
+	body := `{"06-24":["Jānis"]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` && body == `{"06-24":["Jānis"]}` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	sync := NewDatasetSync(db, server.URL, "", "lv", true, time.Hour)
+
+	report, err := sync.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce returned an error: %v", err)
+	}
+	if report == nil || report.Added != 1 {
+		t.Errorf("Expected one added name, got %+v", report)
+	}
+
+	// Unchanged datasets are skipped
+	if report, err = sync.RunOnce(context.Background()); err != nil || report != nil {
+		t.Errorf("Expected no report for an unchanged dataset, got %+v (%v)", report, err)
+	}
+
+	body = `{"06-24":["Jāņa"]}`
+	if report, err = sync.RunOnce(context.Background()); err != nil || report == nil || report.Added != 1 || report.Removed != 1 {
+		t.Errorf("Expected the changed dataset to be applied, got %+v (%v)", report, err)
+	}
+
+	names, _ := getNamedaysForDate(db, "lv", "06-24")
+	if len(names) != 1 || names[0] != "Jāņa" {
+		t.Errorf("Expected [Jāņa], got %v", names)
+	}
+}
+
+func TestDatasetSyncRetriesFailedImport(t *testing.T) {
+	_, db := createTestDb(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"06-24":["Jānis"]}`))
+	}))
+	defer server.Close()
+
+	sync := NewDatasetSync(db, server.URL, "", "", false, time.Hour)
+	if _, err := sync.RunOnce(context.Background()); err == nil {
+		t.Fatal("Expected an error for an import without a country")
+	}
+
+	sync.country = "lv"
+	if report, err := sync.RunOnce(context.Background()); err != nil || report == nil {
+		t.Errorf("Expected the dataset to be imported on retry, got %+v (%v)", report, err)
+	}
+}
+
+func TestDatasetSyncChecksum(t *testing.T) {
+	_, db := createTestDb(t)
+	body := `{"06-24":["Jānis"]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	sync := NewDatasetSync(db, server.URL, strings.Repeat("0", 64), "lv", false, time.Hour)
+	if _, err := sync.RunOnce(context.Background()); !errors.Is(err, importer.ChecksumMismatchErr) {
+		t.Fatalf("Expected ChecksumMismatchErr, got %v", err)
+	}
+	if names, _ := getNamedaysForDate(db, "lv", "06-24"); len(names) != 0 {
+		t.Errorf("Expected nothing to be imported, got %v", names)
+	}
+
+	sum := sha256.Sum256([]byte(body))
+	sync = NewDatasetSync(db, server.URL, hex.EncodeToString(sum[:]), "lv", false, time.Hour)
+	if report, err := sync.RunOnce(context.Background()); err != nil || report == nil || report.Added != 1 {
+		t.Errorf("Expected the dataset to be imported, got %+v (%v)", report, err)
+	}
+}
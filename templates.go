@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+)
+
+//go:embed templates/*.html templates/email/*
+var templateFS embed.FS
+
+// pages maps a page name to its template, each combined with the shared
+// layout. html/template escapes every value, so names coming from the
+// database cannot inject markup.
+var pages = map[string]*template.Template{
+	"home":         parsePage("home.html"),
+	"list":         parsePage("list.html"),
+	"month":        parsePage("month.html"),
+	"subscription": parsePage("subscription.html"),
+}
+
+func parsePage(file string) *template.Template {
+	return template.Must(template.ParseFS(templateFS, "templates/layout.html", "templates/"+file))
+}
+
+// renderPage executes the named page with data into a byte slice
+func renderPage(page string, data interface{}) ([]byte, error) {
+	tmpl, ok := pages[page]
+	if !ok {
+		return nil, fmt.Errorf("unknown page %q", page)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s page: %w", page, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writePage renders the named page and writes it as an HTML response
+func writePage(w http.ResponseWriter, r *http.Request, page string, data interface{}) {
+	html, err := renderPage(page, data)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(html)
+}
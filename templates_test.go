@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const xssName = `<script>alert("x")</script>`
+
+func TestRenderHTMLListEscapesItems(t *testing.T) {
+	result := RenderHTMLList([]string{xssName})
+
+	if strings.Contains(result, "<script>") {
+		t.Errorf("RenderHTMLList output contains unescaped markup: %s", result)
+	}
+	if !strings.Contains(result, "&lt;script&gt;") {
+		t.Errorf("RenderHTMLList output does not contain the escaped item: %s", result)
+	}
+}
+
+func TestHomeHandlerEscapesNames(t *testing.T) {
+	tmpDBPath, db := createTestDb(t)
+
+	if _, err := db.Exec("INSERT INTO namedays (date, name) VALUES (?, ?)", "06-24", xssName); err != nil {
+		t.Fatal("Failed to insert test data:", err)
+	}
+
+	handler := NewHomeHandler(tmpDBPath)
+	handler.clock = fixedClock(midsummer)
+	rr, req := setupTestRequest(t, http.MethodGet, "/", nil)
+	handler.ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	if ct := rr.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Unexpected content type: %s", ct)
+	}
+
+	body := rr.Body.String()
+	if strings.Contains(body, "<script>") {
+		t.Errorf("Home page contains unescaped markup: %s", body)
+	}
+	if !strings.Contains(body, "<li>&lt;script&gt;") {
+		t.Errorf("Home page does not contain the escaped name: %s", body)
+	}
+}
+
+func TestHomeHandlerNoNamedays(t *testing.T) {
+	tmpDBPath, _ := createTestDb(t)
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/", nil)
+	NewHomeHandler(tmpDBPath).ServeHTTP(rr, req)
+
+	checkResponseStatus(t, rr, http.StatusOK)
+	if !strings.Contains(rr.Body.String(), "No namedays found for today") {
+		t.Error("Expected empty state message")
+	}
+}
+
+func TestEveryPageUsesLayout(t *testing.T) {
+	for page := range pages {
+		html, err := renderPage(page, nil)
+		if err != nil {
+			t.Fatalf("Failed to render %s page: %v", page, err)
+		}
+		if !strings.HasPrefix(string(html), "<!DOCTYPE html>") {
+			t.Errorf("Page %s does not use the shared layout", page)
+		}
+	}
+}
+
+func TestRenderUnknownPage(t *testing.T) {
+	if _, err := renderPage("missing", nil); err == nil {
+		t.Error("Expected an error for an unknown page")
+	}
+}
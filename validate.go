@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gosimple/slug"
+
+	"github.com/zrks/namedays/pkg/namedays"
+)
+
+const (
+	// maxNamedayBody caps the size of a nameday create or update body
+	maxNamedayBody = 4 << 10
+	// maxNameLength is the longest name accepted, in characters
+	maxNameLength = 64
+)
+
+// FieldError explains why one field of a request body was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validateNameday checks a nameday payload and normalizes its name and
+// country, returning an error for every invalid field
+func validateNameday(nameday *namedays.Nameday) []FieldError {
+	var errs []FieldError
+
+	nameday.Name = strings.TrimSpace(nameday.Name)
+	if message := validateName(nameday.Name); message != "" {
+		errs = append(errs, FieldError{Field: "name", Message: message})
+	}
+
+	if nameday.Date == "" {
+		errs = append(errs, FieldError{Field: "date", Message: "is required"})
+	} else if !MonthDayRe.MatchString(nameday.Date) {
+		errs = append(errs, FieldError{Field: "date", Message: "must be MM-DD"})
+	} else if _, err := ParseMonthDay(nameday.Date); err != nil {
+		errs = append(errs, FieldError{Field: "date", Message: "is not a day of the year"})
+	}
+
+	nameday.Country = strings.ToLower(nameday.Country)
+	if _, ok := Countries[nameday.Country]; nameday.Country != "" && !ok {
+		errs = append(errs, FieldError{Field: "country", Message: fmt.Sprintf("unsupported country %q", nameday.Country)})
+	}
+	return errs
+}
+
+// validateName accepts names made of letters, hyphens and apostrophes that
+// start with a letter, such as Anna-Marija or O'Neil
+func validateName(name string) string {
+	if name == "" {
+		return "is required"
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return fmt.Sprintf("must be at most %d characters", maxNameLength)
+	}
+
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r):
+		case i == 0:
+			return "must start with a letter"
+		case unicode.Is(unicode.Mn, r), r == '-', r == '\'', r == '’':
+		default:
+			return "may only contain letters, hyphens and apostrophes"
+		}
+	}
+
+	// Some letters, such as ʼ or 々, transliterate to nothing and would
+	// leave the nameday without a key to reach it by
+	if slug.Make(name) == "" {
+		return "must contain a letter that can be spelled in Latin script"
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zrks/namedays/pkg/namedays"
+)
+
+func TestValidateNameday(t *testing.T) {
+	addTestCountries(t)
+	tests := []struct {
+		nameday  namedays.Nameday
+		expected []FieldError
+	}{
+		{namedays.Nameday{Name: "Jānis", Date: "06-24"}, nil},
+		{namedays.Nameday{Name: "Anna-Marija", Date: "02-29", Country: "LT"}, nil},
+		{namedays.Nameday{Name: "O'Neil", Date: "01-01"}, nil},
+		{namedays.Nameday{Name: "Zoë", Date: "12-31"}, nil},
+		{namedays.Nameday{Name: "", Date: ""}, []FieldError{{"name", "is required"}, {"date", "is required"}}},
+		{namedays.Nameday{Name: "   ", Date: "06-24"}, []FieldError{{"name", "is required"}}},
+		{namedays.Nameday{Name: "-Anna", Date: "06-24"}, []FieldError{{"name", "must start with a letter"}}},
+		{namedays.Nameday{Name: "John Smith", Date: "06-24"}, []FieldError{{"name", "may only contain letters, hyphens and apostrophes"}}},
+		{namedays.Nameday{Name: "R2D2", Date: "06-24"}, []FieldError{{"name", "may only contain letters, hyphens and apostrophes"}}},
+		{namedays.Nameday{Name: "ʼʻ", Date: "06-24"}, []FieldError{{"name", "must contain a letter that can be spelled in Latin script"}}},
+		{namedays.Nameday{Name: "ー々", Date: "06-24"}, []FieldError{{"name", "must contain a letter that can be spelled in Latin script"}}},
+		{namedays.Nameday{Name: strings.Repeat("ā", maxNameLength+1), Date: "06-24"}, []FieldError{{"name", "must be at most 64 characters"}}},
+		{namedays.Nameday{Name: "Anna", Date: "banana"}, []FieldError{{"date", "must be MM-DD"}}},
+		{namedays.Nameday{Name: "Anna", Date: "2024-06-24"}, []FieldError{{"date", "must be MM-DD"}}},
+		{namedays.Nameday{Name: "Anna", Date: "02-30"}, []FieldError{{"date", "is not a day of the year"}}},
+		{namedays.Nameday{Name: "Anna", Date: "13-01"}, []FieldError{{"date", "is not a day of the year"}}},
+		{namedays.Nameday{Name: "Anna", Date: "07-26", Country: "xx"}, []FieldError{{"country", `unsupported country "xx"`}}},
+	}
+
+	for _, tt := range tests {
+		nameday := tt.nameday
+		if errs := validateNameday(&nameday); !reflect.DeepEqual(errs, tt.expected) {
+			t.Errorf("validateNameday(%+v) = %v, want %v", tt.nameday, errs, tt.expected)
+		}
+	}
+}
+
+func TestValidateNamedayNormalizes(t *testing.T) {
+	addTestCountries(t)
+	nameday := namedays.Nameday{Name: " Ona ", Date: "02-01", Country: "LT"}
+	if errs := validateNameday(&nameday); errs != nil {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+	if nameday.Name != "Ona" || nameday.Country != "lt" {
+		t.Errorf("Expected the name trimmed and the country lowercased, got %+v", nameday)
+	}
+}
+
+func TestNamedayHandlerRejectsInvalidPayloads(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		status   int
+		expected []FieldError
+	}{
+		{"invalid fields", http.MethodPost, "/nameday", `{"name": "", "date": "banana"}`, http.StatusUnprocessableEntity,
+			[]FieldError{{"name", "is required"}, {"date", "must be MM-DD"}}},
+		{"name without a slug", http.MethodPost, "/nameday", `{"name": "々", "date": "07-26"}`, http.StatusUnprocessableEntity,
+			[]FieldError{{"name", "must contain a letter that can be spelled in Latin script"}}},
+		{"unknown field", http.MethodPost, "/nameday", `{"name": "Anna", "date": "07-26", "admin": true}`, http.StatusUnprocessableEntity,
+			[]FieldError{{"admin", "is not a nameday field"}}},
+		{"wrong field type", http.MethodPost, "/nameday", `{"name": "Anna", "date": 726}`, http.StatusUnprocessableEntity,
+			[]FieldError{{"date", "must be a string"}}},
+		{"invalid update", http.MethodPut, johnSmithPath, `{"name": "John-Smith", "date": "02-30"}`, http.StatusUnprocessableEntity,
+			[]FieldError{{"date", "is not a day of the year"}}},
+		{"body too large", http.MethodPost, "/nameday", `{"name": "` + strings.Repeat("a", maxNamedayBody) + `", "date": "07-26"}`, http.StatusRequestEntityTooLarge,
+			nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, handler := createTestNamedayHandler()
+			addTestNameday(store, johnSmithKey, testJohnSmith, "04-12")
+
+			rr, req := setupTestRequest(t, tt.method, tt.path, []byte(tt.body))
+			handler.ServeHTTP(rr, req)
+
+			checkResponseStatus(t, rr, tt.status)
+			if problem := readTestProblem(t, rr); !reflect.DeepEqual(problem.Errors, tt.expected) {
+				t.Errorf("Expected errors %v, got %v", tt.expected, problem.Errors)
+			}
+
+			// Nothing may be stored by a rejected request
+			if list, _ := store.List(""); len(list) != 1 || list[johnSmithKey].Date != "04-12" {
+				t.Errorf("Expected the store to be unchanged, got %v", list)
+			}
+		})
+	}
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	WebhooksRe          = regexp.MustCompile(`^/api/v1/webhooks/?$`)
+	WebhookRe           = regexp.MustCompile(`^/api/v1/webhooks/([0-9]+)$`)
+	WebhookDeliveriesRe = regexp.MustCompile(`^/api/v1/webhooks/([0-9]+)/deliveries$`)
+
+	WebhookNotFoundErr = errors.New("webhook not found")
+)
+
+// Webhook is a subscription to the daily namedays. When List is set only
+// the contacts of that list who celebrate are sent, and days on which
+// none of them do are skipped.
+type Webhook struct {
+	ID        int64  `json:"id"`
+	URL       string `json:"url"`
+	Secret    string `json:"secret,omitempty"`
+	Country   string `json:"country"`
+	List      string `json:"list,omitempty"`
+	Format    string `json:"format"`
+	CreatedAt string `json:"created_at"`
+}
+
+// WebhookDelivery is one logged attempt to deliver a webhook
+type WebhookDelivery struct {
+	ID         int64  `json:"id"`
+	WebhookID  int64  `json:"webhook_id"`
+	Day        string `json:"day"`
+	Attempt    int    `json:"attempt"`
+	StatusCode int    `json:"status_code"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// validateWebhook checks a subscription and fills in the default country
+// and message format. Internal addresses are refused unless allowPrivate.
+func validateWebhook(hook *Webhook, allowPrivate bool) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q", hook.URL)
+	}
+	// Hosts resolving to internal addresses are refused when delivering,
+	// this only catches the obvious ones early
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if ip := net.ParseIP(host); !allowPrivate && (host == "localhost" || strings.HasSuffix(host, ".localhost") || (ip != nil && internalIP(ip))) {
+		return fmt.Errorf("webhook URL %q is not a public address", hook.URL)
+	}
+
+	hook.Country = strings.ToLower(hook.Country)
+	if hook.Country == "" {
+		hook.Country = defaultCountry
+	}
+	if _, ok := Countries[hook.Country]; !ok {
+		return fmt.Errorf("unsupported country %q", hook.Country)
+	}
+
+	if hook.List != "" && !ContactListRe.MatchString(hook.List) {
+		return fmt.Errorf("invalid contact list %q", hook.List)
+	}
+
+	hook.Format = strings.ToLower(hook.Format)
+	if hook.Format == "" {
+		hook.Format = DefaultMessageFormat
+	}
+	if _, ok := MessageFormats[hook.Format]; !ok {
+		return fmt.Errorf("unsupported message format %q", hook.Format)
+	}
+	return nil
+}
+
+// randomToken generates a random hex secret, used to sign webhooks and in
+// subscription links
+func randomToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
+
+func createWebhook(db *sql.DB, hook *Webhook) error {
+	if hook.Secret == "" {
+		secret, err := randomToken()
+		if err != nil {
+			return err
+		}
+		hook.Secret = secret
+	}
+	hook.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	result, err := db.Exec("INSERT INTO webhooks (url, secret, country, list, format, created_at) VALUES (?, ?, ?, ?, ?, ?)", hook.URL, hook.Secret, hook.Country, hook.List, hook.Format, hook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook: %w", err)
+	}
+	hook.ID, err = result.LastInsertId()
+	return err
+}
+
+func getWebhook(db *sql.DB, id int64) (Webhook, error) {
+	var hook Webhook
+	err := db.QueryRow("SELECT id, url, secret, country, list, format, created_at FROM webhooks WHERE id = ?", id).Scan(&hook.ID, &hook.URL, &hook.Secret, &hook.Country, &hook.List, &hook.Format, &hook.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Webhook{}, WebhookNotFoundErr
+	}
+	if err != nil {
+		return Webhook{}, fmt.Errorf("error querying database: %w", err)
+	}
+	return hook, nil
+}
+
+func listWebhooks(db *sql.DB) ([]Webhook, error) {
+	rows, err := db.Query("SELECT id, url, secret, country, list, format, created_at FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		var hook Webhook
+		if err := rows.Scan(&hook.ID, &hook.URL, &hook.Secret, &hook.Country, &hook.List, &hook.Format, &hook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return hooks, nil
+}
+
+// updateWebhook replaces a subscription, keeping its secret unless a new
+// one is given
+func updateWebhook(db *sql.DB, hook *Webhook) error {
+	existing, err := getWebhook(db, hook.ID)
+	if err != nil {
+		return err
+	}
+	if hook.Secret == "" {
+		hook.Secret = existing.Secret
+	}
+	hook.CreatedAt = existing.CreatedAt
+
+	if _, err = db.Exec("UPDATE webhooks SET url = ?, secret = ?, country = ?, list = ?, format = ? WHERE id = ?", hook.URL, hook.Secret, hook.Country, hook.List, hook.Format, hook.ID); err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+func deleteWebhook(db *sql.DB, id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	result, err := tx.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to remove webhook: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return WebhookNotFoundErr
+	}
+	if _, err = tx.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to remove webhook deliveries: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func logWebhookDelivery(db *sql.DB, delivery WebhookDelivery) error {
+	if _, err := db.Exec("INSERT INTO webhook_deliveries (webhook_id, day, attempt, status, success, error, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		delivery.WebhookID, delivery.Day, delivery.Attempt, delivery.StatusCode, delivery.Success, delivery.Error, delivery.CreatedAt); err != nil {
+		return fmt.Errorf("failed to log webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// listWebhookDeliveries returns the delivery log of a webhook, newest first
+func listWebhookDeliveries(db *sql.DB, id int64) ([]WebhookDelivery, error) {
+	rows, err := db.Query("SELECT id, webhook_id, day, attempt, status, success, error, created_at FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC", id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying database: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Day, &d.Attempt, &d.StatusCode, &d.Success, &d.Error, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// deliveredOn reports whether a webhook was successfully delivered for a day
+func deliveredOn(db *sql.DB, id int64, day string) (bool, error) {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = ? AND day = ? AND success = 1", id, day).Scan(&count); err != nil {
+		return false, fmt.Errorf("error querying database: %w", err)
+	}
+	return count > 0, nil
+}
+
+// WebhookHandler serves the webhook subscriptions API. Secrets are only
+// returned when a webhook is created.
+type WebhookHandler struct {
+	db        *sql.DB
+	scheduler *webhookScheduler
+}
+
+func NewWebhookHandler(db *sql.DB, scheduler *webhookScheduler) *WebhookHandler {
+	return &WebhookHandler{db: db, scheduler: scheduler}
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && WebhooksRe.MatchString(r.URL.Path):
+		h.CreateWebhook(w, r)
+	case r.Method == http.MethodGet && WebhooksRe.MatchString(r.URL.Path):
+		h.ListWebhooks(w, r)
+	case r.Method == http.MethodGet && WebhookRe.MatchString(r.URL.Path):
+		h.GetWebhook(w, r)
+	case r.Method == http.MethodPut && WebhookRe.MatchString(r.URL.Path):
+		h.UpdateWebhook(w, r)
+	case r.Method == http.MethodDelete && WebhookRe.MatchString(r.URL.Path):
+		h.DeleteWebhook(w, r)
+	case r.Method == http.MethodGet && WebhookDeliveriesRe.MatchString(r.URL.Path):
+		h.ListDeliveries(w, r)
+	case r.Method == http.MethodPost && WebhookDeliveriesRe.MatchString(r.URL.Path):
+		h.Deliver(w, r)
+	default:
+		NotFoundHandler(w, r)
+	}
+}
+
+// webhookID extracts the webhook ID from the request path
+func webhookID(re *regexp.Regexp, r *http.Request) (int64, bool) {
+	matches := re.FindStringSubmatch(r.URL.Path)
+	if len(matches) < 2 {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(matches[1], 10, 64)
+	return id, err == nil
+}
+
+// decodeWebhook reads and validates a subscription from the request body
+func decodeWebhook(r *http.Request, allowPrivate bool) (Webhook, error) {
+	var hook Webhook
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&hook); err != nil {
+		return Webhook{}, err
+	}
+	if err := validateWebhook(&hook, allowPrivate); err != nil {
+		return Webhook{}, err
+	}
+	return hook, nil
+}
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, err := decodeWebhook(r, h.scheduler.allowPrivate)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	if err = createWebhook(h.db, &hook); err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/webhooks/%d", hook.ID))
+	writeJSON(w, r, http.StatusCreated, hook)
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := listWebhooks(h.db)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	writeJSON(w, r, http.StatusOK, hooks)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(WebhookRe, r)
+	if !ok {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	hook, err := getWebhook(h.db, id)
+	if err == WebhookNotFoundErr {
+		NotFoundHandler(w, r)
+		return
+	}
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	hook.Secret = ""
+	writeJSON(w, r, http.StatusOK, hook)
+}
+
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(WebhookRe, r)
+	if !ok {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	hook, err := decodeWebhook(r, h.scheduler.allowPrivate)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	hook.ID = id
+	err = updateWebhook(h.db, &hook)
+	if err == WebhookNotFoundErr {
+		NotFoundHandler(w, r)
+		return
+	}
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	hook.Secret = ""
+	writeJSON(w, r, http.StatusOK, hook)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(WebhookRe, r)
+	if !ok {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	err := deleteWebhook(h.db, id)
+	if err == WebhookNotFoundErr {
+		NotFoundHandler(w, r)
+		return
+	}
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(WebhookDeliveriesRe, r)
+	if !ok {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	if _, err := getWebhook(h.db, id); err == WebhookNotFoundErr {
+		NotFoundHandler(w, r)
+		return
+	} else if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	deliveries, err := listWebhookDeliveries(h.db, id)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, deliveries)
+}
+
+// Deliver sends today's namedays to a webhook immediately, which is handy
+// for checking a receiver without waiting for the scheduled time. It makes
+// a single attempt so the request is not held open through the retries.
+func (h *WebhookHandler) Deliver(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(WebhookDeliveriesRe, r)
+	if !ok {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	hook, err := getWebhook(h.db, id)
+	if err == WebhookNotFoundErr {
+		NotFoundHandler(w, r)
+		return
+	}
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	now, err := requestNow(h.scheduler.clock, r)
+	if err != nil {
+		BadRequestHandler(w, r)
+		return
+	}
+
+	if _, err = h.scheduler.DeliverOnce(r.Context(), hook, now); err != nil {
+		log.Printf("Webhook %d delivery failed: %v", hook.ID, err)
+	}
+
+	deliveries, err := listWebhookDeliveries(h.db, id)
+	if err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, deliveries)
+}
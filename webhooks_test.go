@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func createTestWebhookHandler(t *testing.T) (*sql.DB, *WebhookHandler) {
+	_, db := createTestDb(t)
+	scheduler, err := NewWebhookScheduler(db, DefaultWebhookTime, false)
+	if err != nil {
+		t.Fatalf("NewWebhookScheduler returned an error: %v", err)
+	}
+	return db, NewWebhookHandler(db, scheduler)
+}
+
+func createTestWebhook(t *testing.T, handler http.Handler, body string) Webhook {
+	rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/webhooks", []byte(body))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusCreated)
+
+	var hook Webhook
+	if err := json.Unmarshal(rr.Body.Bytes(), &hook); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	return hook
+}
+
+func TestWebhookHandlerCRUD(t *testing.T) {
+	addTestCountries(t)
+	_, handler := createTestWebhookHandler(t)
+
+	hook := createTestWebhook(t, handler, `{"url":"https://chat.example.com/hook","list":"team"}`)
+	if hook.ID == 0 || hook.Country != "lv" || hook.List != "team" || hook.Format != DefaultMessageFormat {
+		t.Errorf("Unexpected webhook: %v", hook)
+	}
+	if len(hook.Secret) != 64 {
+		t.Errorf("Expected a generated secret, got %q", hook.Secret)
+	}
+
+	rr, req := setupTestRequest(t, http.MethodGet, "/api/v1/webhooks/1", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	var retrieved Webhook
+	if err := json.Unmarshal(rr.Body.Bytes(), &retrieved); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if retrieved.URL != hook.URL || retrieved.Secret != "" {
+		t.Errorf("Expected the webhook without its secret, got %v", retrieved)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodPut, "/api/v1/webhooks/1", []byte(`{"url":"https://chat.example.com/other","country":"LT","format":"slack"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/api/v1/webhooks", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+	var hooks []Webhook
+	if err := json.Unmarshal(rr.Body.Bytes(), &hooks); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(hooks) != 1 || hooks[0].URL != "https://chat.example.com/other" || hooks[0].Country != "lt" || hooks[0].List != "" || hooks[0].Format != "slack" {
+		t.Errorf("Expected the updated webhook, got %v", hooks)
+	}
+
+	rr, req = setupTestRequest(t, http.MethodDelete, "/api/v1/webhooks/1", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusNoContent)
+
+	rr, req = setupTestRequest(t, http.MethodGet, "/api/v1/webhooks/1", nil)
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusNotFound)
+}
+
+func TestWebhookHandlerKeepsSecretOnUpdate(t *testing.T) {
+	db, handler := createTestWebhookHandler(t)
+	createTestWebhook(t, handler, `{"url":"https://chat.example.com/hook","secret":"s3cret"}`)
+
+	rr, req := setupTestRequest(t, http.MethodPut, "/api/v1/webhooks/1", []byte(`{"url":"https://chat.example.com/hook"}`))
+	handler.ServeHTTP(rr, req)
+	checkResponseStatus(t, rr, http.StatusOK)
+
+	hook, err := getWebhook(db, 1)
+	if err != nil {
+		t.Fatalf("getWebhook returned an error: %v", err)
+	}
+	if hook.Secret != "s3cret" {
+		t.Errorf("Expected the secret to be kept, got %q", hook.Secret)
+	}
+}
+
+func TestWebhookHandlerAllowPrivate(t *testing.T) {
+	_, db := createTestDb(t)
+	scheduler, err := NewWebhookScheduler(db, DefaultWebhookTime, true)
+	if err != nil {
+		t.Fatalf("NewWebhookScheduler returned an error: %v", err)
+	}
+	handler := NewWebhookHandler(db, scheduler)
+
+	for _, body := range []string{`{"url":"http://localhost:8080/hook"}`, `{"url":"https://10.0.0.8/hook"}`} {
+		rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/webhooks", []byte(body))
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Errorf("Expected 201 for %s, got %d", body, rr.Code)
+		}
+	}
+}
+
+func TestWebhookHandlerInvalidRequests(t *testing.T) {
+	_, handler := createTestWebhookHandler(t)
+
+	invalid := []string{
+		`{"url":"ftp://example.com"}`,
+		`{"url":"/relative"}`,
+		`{"url":"https://example.com","country":"xx"}`,
+		`{"url":"https://example.com","list":"no/such"}`,
+		`{"url":"https://example.com","colour":"red"}`,
+		`{"url":"https://example.com","format":"telex"}`,
+		`{"url":"http://localhost:8080/hook"}`,
+		`{"url":"http://127.0.0.1/hook"}`,
+		`{"url":"http://[::1]/hook"}`,
+		`{"url":"http://169.254.169.254/latest/meta-data"}`,
+		`{"url":"https://10.0.0.8/hook"}`,
+		`not json`,
+	}
+	for _, body := range invalid {
+		rr, req := setupTestRequest(t, http.MethodPost, "/api/v1/webhooks", []byte(body))
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		rr, req := setupTestRequest(t, method, "/api/v1/webhooks/42", []byte(`{"url":"https://example.com"}`))
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s of a missing webhook, got %d", method, rr.Code)
+		}
+	}
+}